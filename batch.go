@@ -0,0 +1,196 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"fmt"
+)
+
+// PointError is a point in a batch that couldn't be projected.
+type PointError struct {
+	Index int
+	Err   error
+}
+
+// BatchError lists the points the batch functions couldn't project, in
+// order.  They're set to +Inf, like Transform skips, and the rest of the
+// batch is projected as normal.
+type BatchError []PointError
+
+func (e BatchError) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("point %d could not be projected: %v", e[0].Index, e[0].Err)
+	}
+	return fmt.Sprintf("%d points could not be projected, the first at %d: %v", len(e), e[0].Index, e[0].Err)
+}
+
+// Unwrap lets errors.Is and errors.As see why each point failed.
+func (e BatchError) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, pe := range e {
+		errs[i] = pe.Err
+	}
+	return errs
+}
+
+// translators is an implementation that projects through commonFwd and
+// commonInv, which is all of them but Geocentric.
+type translators interface {
+	fwd(lam, phi float64) (x, y float64, err error)
+	inv(x, y float64) (lam, phi float64, err error)
+}
+
+// ForwardSlice projects the points in lng and lat with p in place.  If p
+// is a BatchProjector, as the projections this package makes are, the
+// setup Forward does for every point, like finding the translator and the
+// axis order, is done once for the batch; that's only noticeable for the
+// cheapest projections, where it's a few percent, as the projection itself
+// is most of the work.  Otherwise the points go through p.Forward one by
+// one.
+func ForwardSlice(p Projection, lng, lat []float64) error {
+	if bp, ok := p.(BatchProjector); ok {
+		return bp.ForwardSlice(lng, lat)
+	}
+	if len(lng) != len(lat) {
+		return argError("ForwardSlice", "lat", ErrInvalidParam)
+	}
+	return batch(p.Forward, lng, lat, len(lng), 1)
+}
+
+// InverseSlice projects the points in x and y with p back to lng/lat in
+// place, in the same way as ForwardSlice.
+func InverseSlice(p Projection, x, y []float64) error {
+	if bp, ok := p.(BatchProjector); ok {
+		return bp.InverseSlice(x, y)
+	}
+	if len(x) != len(y) {
+		return argError("InverseSlice", "y", ErrInvalidParam)
+	}
+	return batch(p.Inverse, x, y, len(x), 1)
+}
+
+// ForwardStrided projects the points in coords with p in place, which
+// start every stride values with lng then lat; 2 for interleaved pairs, 3
+// if they come with heights.
+func ForwardStrided(p Projection, coords []float64, stride int) error {
+	if bp, ok := p.(BatchProjector); ok {
+		return bp.ForwardStrided(coords, stride)
+	}
+	n, err := strided("ForwardStrided", coords, stride)
+	if err != nil || n == 0 {
+		return err
+	}
+	return batch(p.Forward, coords, coords[1:], n, stride)
+}
+
+// InverseStrided is the inverse of ForwardStrided.
+func InverseStrided(p Projection, coords []float64, stride int) error {
+	if bp, ok := p.(BatchProjector); ok {
+		return bp.InverseStrided(coords, stride)
+	}
+	n, err := strided("InverseStrided", coords, stride)
+	if err != nil || n == 0 {
+		return err
+	}
+	return batch(p.Inverse, coords, coords[1:], n, stride)
+}
+
+func (p *pj) ForwardSlice(lng, lat []float64) error {
+	if len(lng) != len(lat) {
+		return argError("ForwardSlice", "lat", ErrInvalidParam)
+	}
+	return p.batch(false, lng, lat, len(lng), 1)
+}
+
+func (p *pj) InverseSlice(x, y []float64) error {
+	if len(x) != len(y) {
+		return argError("InverseSlice", "y", ErrInvalidParam)
+	}
+	return p.batch(true, x, y, len(x), 1)
+}
+
+func (p *pj) ForwardStrided(coords []float64, stride int) error {
+	n, err := strided("ForwardStrided", coords, stride)
+	if err != nil || n == 0 {
+		return err
+	}
+	return p.batch(false, coords, coords[1:], n, stride)
+}
+
+func (p *pj) InverseStrided(coords []float64, stride int) error {
+	n, err := strided("InverseStrided", coords, stride)
+	if err != nil || n == 0 {
+		return err
+	}
+	return p.batch(true, coords, coords[1:], n, stride)
+}
+
+// strided is the number of points in coords, given to fn.
+func strided(fn string, coords []float64, stride int) (int, error) {
+	if stride < 2 || len(coords)%stride != 0 {
+		return 0, argError(fn, "stride", ErrInvalidParam)
+	}
+	return len(coords) / stride, nil
+}
+
+// batch projects n points with p's translators, or takes them back with
+// the inverse ones.  Only Geocentric has no translators, and its Forward
+// and Inverse just fail, so it goes point by point.
+func (p *pj) batch(inverse bool, x, y []float64, n, stride int) error {
+	tr := p.forward
+	if inverse {
+		tr = p.inverse
+	}
+	if tr == nil {
+		g := &Geocentric{p}
+		if inverse {
+			return batch(g.Inverse, x, y, n, stride)
+		}
+		return batch(g.Forward, x, y, n, stride)
+	}
+	pr := p.projector(tr)
+	return pr.batch(inverse, x, y, n, stride)
+}
+
+// batch runs tr over n points in place, the i'th of which is at
+// x[i*stride], y[i*stride].
+func batch(tr translator, x, y []float64, n, stride int) error {
+	var errs BatchError
+	for i := 0; i < n; i++ {
+		j := i * stride
+		var err error
+		if x[j], y[j], err = tr(x[j], y[j]); err != nil {
+			x[j], y[j] = hugeVal, hugeVal
+			errs = append(errs, PointError{i, err})
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// batch is batch with pr.fwd, or pr.inv if inverse is set, called
+// directly rather than through a translator.
+func (pr *projector) batch(inverse bool, x, y []float64, n, stride int) error {
+	var errs BatchError
+	for i := 0; i < n; i++ {
+		j := i * stride
+		var err error
+		if inverse {
+			x[j], y[j], err = pr.inv(x[j], y[j])
+		} else {
+			x[j], y[j], err = pr.fwd(x[j], y[j])
+		}
+		if err != nil {
+			x[j], y[j] = hugeVal, hugeVal
+			errs = append(errs, PointError{i, err})
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"errors"
+	"testing"
+)
+
+// batchPoints are lng/lat in radians, spread over Europe
+func batchPoints(n int) (lng, lat []float64) {
+	lng, lat = make([]float64, n), make([]float64, n)
+	for i := range lng {
+		lng[i] = (-10 + 40*float64(i%97)/97) * d2r
+		lat[i] = (35 + 30*float64(i%89)/89) * d2r
+	}
+	return lng, lat
+}
+
+func TestForwardSlice(t *testing.T) {
+	pj, err := NewProjection("+proj=laea +lat_0=52 +lon_0=10 +x_0=4321000 +y_0=3210000 +ellps=GRS80 +axis=neu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lng, lat := batchPoints(500)
+	x, y := append([]float64(nil), lng...), append([]float64(nil), lat...)
+	if err := pj.(BatchProjector).ForwardSlice(x, y); err != nil {
+		t.Fatal(err)
+	}
+	interleaved := make([]float64, 0, 3*len(lng))
+	for i := range lng {
+		interleaved = append(interleaved, lng[i], lat[i], float64(i))
+	}
+	if err := ForwardStrided(pj, interleaved, 3); err != nil {
+		t.Fatal(err)
+	}
+	for i := range lng {
+		ex, ey, _ := pj.Forward(lng[i], lat[i])
+		if x[i] != ex || y[i] != ey {
+			t.Fatalf("point %d: expected (%f, %f), got (%f, %f)", i, ex, ey, x[i], y[i])
+		}
+		if interleaved[3*i] != ex || interleaved[3*i+1] != ey || interleaved[3*i+2] != float64(i) {
+			t.Fatalf("point %d: expected (%f, %f, %d), got %v", i, ex, ey, i, interleaved[3*i:3*i+3])
+		}
+	}
+
+	if err := pj.(BatchProjector).InverseSlice(x, y); err != nil {
+		t.Fatal(err)
+	}
+	if err := InverseStrided(pj, interleaved, 3); err != nil {
+		t.Fatal(err)
+	}
+	for i := range lng {
+		if !within(lng[i], x[i], 1e-11) || !within(lat[i], y[i], 1e-11) {
+			t.Fatalf("point %d: expected (%f, %f), got (%f, %f)", i, lng[i], lat[i], x[i], y[i])
+		}
+		if interleaved[3*i] != x[i] || interleaved[3*i+1] != y[i] {
+			t.Fatalf("point %d: expected (%f, %f), got %v", i, x[i], y[i], interleaved[3*i:3*i+2])
+		}
+	}
+}
+
+// scalarOnly hides everything but Projection, like an implementation from
+// outside the package.
+type scalarOnly struct {
+	Projection
+}
+
+func TestBatchFallback(t *testing.T) {
+	pj, err := NewProjection("+proj=merc +lon_0=10 +ellps=WGS84")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lng, lat := batchPoints(50)
+	x, y := append([]float64(nil), lng...), append([]float64(nil), lat...)
+	if err := ForwardSlice(scalarOnly{pj}, x, y); err != nil {
+		t.Fatal(err)
+	}
+	coords := make([]float64, 0, 2*len(lng))
+	for i := range lng {
+		coords = append(coords, lng[i], lat[i])
+	}
+	if err := ForwardStrided(scalarOnly{pj}, coords, 2); err != nil {
+		t.Fatal(err)
+	}
+	for i := range lng {
+		ex, ey, _ := pj.Forward(lng[i], lat[i])
+		if x[i] != ex || y[i] != ey || coords[2*i] != ex || coords[2*i+1] != ey {
+			t.Fatalf("point %d: expected (%f, %f), got (%f, %f) and %v", i, ex, ey, x[i], y[i], coords[2*i:2*i+2])
+		}
+	}
+	if err := InverseSlice(scalarOnly{pj}, x, y[1:]); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam for mismatched slices, got %v", err)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	pj, err := NewProjection("+proj=ortho +lat_0=40 +lon_0=-100 +R=6371000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second and fourth are on the far side of the world
+	lng := []float64{-90 * d2r, 80 * d2r, -100 * d2r, 60 * d2r}
+	lat := []float64{45 * d2r, -40 * d2r, 40 * d2r, -20 * d2r}
+	pairs := []float64{lng[0], lat[0], lng[1], lat[1], lng[2], lat[2], lng[3], lat[3]}
+
+	err = ForwardSlice(pj, lng, lat)
+	var batchErr BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a BatchError, got %v", err)
+	}
+	if len(batchErr) != 2 || batchErr[0].Index != 1 || batchErr[1].Index != 3 {
+		t.Fatalf("expected errors at 1 and 3, got %v", batchErr)
+	}
+	if !errors.Is(err, ErrPointNotVisible) {
+		t.Errorf("expected ErrPointNotVisible, got %v", batchErr[0].Err)
+	}
+	if lng[1] != hugeVal || lat[1] != hugeVal || lng[0] == hugeVal || lng[2] != 0 {
+		t.Errorf("expected only the failures to be HUGE_VAL, got %v, %v", lng, lat)
+	}
+
+	err = ForwardStrided(pj, pairs, 2)
+	if !errors.As(err, &batchErr) || len(batchErr) != 2 || batchErr[0].Index != 1 || batchErr[1].Index != 3 {
+		t.Errorf("expected errors at 1 and 3, got %v", err)
+	}
+
+	// the failures stay failed
+	err = InverseSlice(pj, lng, lat)
+	if !errors.As(err, &batchErr) || len(batchErr) != 2 || batchErr[0].Index != 1 || batchErr[1].Index != 3 {
+		t.Errorf("expected errors at 1 and 3, got %v", err)
+	}
+	if !within(-90*d2r, lng[0], 1e-11) || !within(40*d2r, lat[2], 1e-11) {
+		t.Errorf("expected the rest to come back, got %v, %v", lng, lat)
+	}
+
+	if err := ForwardSlice(pj, make([]float64, 2), make([]float64, 3)); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam for mismatched slices, got %v", err)
+	}
+	for _, stride := range []int{0, 1, 3} {
+		if err := ForwardStrided(pj, make([]float64, 4), stride); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("expected ErrInvalidParam for a stride of %d, got %v", stride, err)
+		}
+	}
+	if err := ForwardStrided(pj, nil, 2); err != nil {
+		t.Errorf("expected nothing to do, got %v", err)
+	}
+
+	// geocent has no projection to batch, so it goes point by point
+	geocent, _ := NewProjection("+proj=geocent +datum=WGS84")
+	if err := ForwardSlice(geocent, []float64{0}, []float64{0}); !errors.Is(err, ErrGeocentric) {
+		t.Errorf("expected ErrGeocentric, got %v", err)
+	}
+}
+
+const benchProj = "+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy"
+
+func BenchmarkForward(b *testing.B) {
+	pj, _ := NewProjection(benchProj)
+	lng, lat := batchPoints(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := range lng {
+			pj.Forward(lng[i], lat[i])
+		}
+	}
+}
+
+func BenchmarkForwardSlice(b *testing.B) {
+	pj, _ := NewProjection(benchProj)
+	lng, lat := batchPoints(1000)
+	x, y := make([]float64, len(lng)), make([]float64, len(lat))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		copy(x, lng)
+		copy(y, lat)
+		ForwardSlice(pj, x, y)
+	}
+}
+
+func BenchmarkForwardStrided(b *testing.B) {
+	pj, _ := NewProjection(benchProj)
+	lng, lat := batchPoints(1000)
+	pairs, coords := make([]float64, 2*len(lng)), make([]float64, 2*len(lng))
+	for i := range lng {
+		pairs[2*i], pairs[2*i+1] = lng[i], lat[i]
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		copy(coords, pairs)
+		ForwardStrided(pj, coords, 2)
+	}
+}
+
+func BenchmarkInverse(b *testing.B) {
+	pj, _ := NewProjection(benchProj)
+	x, y := batchPoints(1000)
+	ForwardSlice(pj, x, y)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := range x {
+			pj.Inverse(x[i], y[i])
+		}
+	}
+}
+
+func BenchmarkInverseSlice(b *testing.B) {
+	pj, _ := NewProjection(benchProj)
+	xs, ys := batchPoints(1000)
+	ForwardSlice(pj, xs, ys)
+	x, y := make([]float64, len(xs)), make([]float64, len(ys))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		copy(x, xs)
+		copy(y, ys)
+		InverseSlice(pj, x, y)
+	}
+}
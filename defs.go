@@ -26,7 +26,7 @@ const (
 type datumType int
 
 const (
-	PJD_UNKNOWN datumType = 0
+	PJD_UNKNOWN datumType = iota
 	PJD_GRIDSHIFT
 	PJD_7PARAM
 	PJD_3PARAM
@@ -36,6 +36,18 @@ const (
 var ErrUnsupportedProj = errors.New("This is not a supported Projection")
 var ErrUnknownDatum = errors.New("This is not a supported datum")
 var ErrInvalidParam = errors.New("We encountered an illegal parameter")
+var ErrToleranceCondition = errors.New("The coordinate is outside the projection's domain")
+var ErrGeocentric = errors.New("Geocentric coordinates need a height, use Transform instead")
+var ErrGridNotFound = errors.New("The grid shift file could not be found")
+var ErrGridFormat = errors.New("The grid shift file is not in a supported format")
+var ErrOutsideGrid = errors.New("The coordinate is outside of the datum shift grids")
+var ErrInvalidWKT = errors.New("The WKT could not be parsed")
+var ErrPointNotVisible = errors.New("The coordinate is not visible from the projection's centre")
+var ErrUnknownCRS = errors.New("This is not a CRS in the EPSG registry")
+var ErrInvalidPROJJSON = errors.New("The PROJJSON could not be parsed")
+var ErrUnknownUnit = errors.New("This is not a supported unit")
+var ErrInvalidCoord = errors.New("The coordinate is out of range")
+var ErrNoConvergence = errors.New("The calculation did not converge")
 
 var hugeVal = math.Inf(1)
 
@@ -64,6 +76,47 @@ func (p paramset) float(s string) (f float64, okay bool) {
 	}
 	return
 }
+
+// ratio reads a number that may be written as a fraction, like 1/3.28.
+func (p paramset) ratio(s string) (f float64, okay bool) {
+	v, ok := p[s]
+	if !ok {
+		return
+	}
+	num, den := v, "1"
+	if i := strings.Index(v, "/"); i >= 0 {
+		num, den = v[:i], v[i+1:]
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return
+	}
+	return n / d, true
+}
+
+// units reads the conversion to metres of the units called name in
+// units_list, or the number toMeter, or returns def if there's neither.
+func (p paramset) units(name, toMeter string, def float64) (float64, error) {
+	if id, ok := p[name]; ok {
+		unit, ok := units_list[id]
+		if !ok {
+			return 0, paramError(name, ErrUnknownUnit)
+		}
+		return unit.to_meter, nil
+	}
+	if _, ok := p[toMeter]; !ok {
+		return def, nil
+	}
+	if f, ok := p.ratio(toMeter); ok && f > 0 {
+		return f, nil
+	}
+	return 0, paramError(toMeter, ErrInvalidParam)
+}
+
 func (p paramset) degree(s string) (f float64, okay bool) {
 	// var err error
 	if v, ok := p[s]; ok {
@@ -91,7 +144,7 @@ func parseDegreeString(ds string) float64 {
 	idx = strings.Index(ds, "\"")
 	if idx >= 0 {
 		f, _ := strconv.ParseFloat(ds[0:idx], 64)
-		res += f / 360
+		res += f / 3600
 		ds = ds[idx+1:]
 	}
 	if strings.HasSuffix(ds, "W") || strings.HasSuffix(ds, "S") {
@@ -0,0 +1,40 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package projectron is a partial port of PROJ to Go.  Projections are made
+from proj4 strings, WKT, PROJJSON or EPSG codes, and Transform moves points
+between them.
+
+# EPSG codes
+
+NewProjection and LookupEPSG know the EPSG registry in two parts.  The
+larger is generated by gen_epsg.go from PROJ's proj.db into epsg_table.go:
+every geographic, geocentric and projected CRS whose method this package
+implements, such as the Swiss LV95 and US State Plane grids.  Run go generate
+with PROJ installed to build or refresh it; a tree without epsg_table.go
+knows only the second part.
+
+The second part is chosen by hand, and its definitions carry the datum
+shifts to WGS 84 that proj.db leaves out, so it takes precedence.  The codes
+are:
+
+	Geographic:    4121 4167 4230 4258 4267 4269 4272 4277 4283 4314
+	               4322 4326 4617 4674
+	Geocentric:    4978
+	World:         3395 3785 3857 4087 32662
+	Polar:         3031 3413 3571-3576 3976 3995
+	Europe:        2100 2154 2180 3003 3006 3034 3035 3067 27700 28992
+	               31370 31467
+	North America: 2163 2227 2263 3338 3347 3348 5070 26986
+	Oceania:       2193 3112 3577
+	UTM:           25828-25838 (ETRS89), 26701-26722 (NAD27),
+	               26901-26923 (NAD83), 28348-28358 (GDA94 MGA),
+	               31977-31985 (SIRGAS 2000), 32601-32660 and
+	               32701-32760 (WGS 84)
+
+Any code in neither part is ErrUnknownCRS; give its proj4 string or WKT
+instead.
+*/
+package projectron
@@ -0,0 +1,284 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run gen_epsg.go
+
+// EPSG describes a coordinate reference system from the EPSG registry.
+type EPSG struct {
+	Code int
+	Name string
+	// Area is the registry's description of where the CRS may be used
+	Area       string
+	Deprecated bool
+	// Definition is the proj4 string for the CRS, with lng/lat in the
+	// traditional GIS order rather than the registry's axis order
+	Definition string
+}
+
+// LookupEPSG finds code in the built-in registry, or returns ErrUnknownCRS.
+// The registry is the codes listed in the package doc and, once it has been
+// generated, the table in epsg_table.go.
+func LookupEPSG(code int) (EPSG, error) {
+	if e, ok := epsgCodes[code]; ok {
+		return e, nil
+	}
+	for _, s := range epsgSeries {
+		if code >= s.first && code <= s.last {
+			zone := s.zone + code - s.first
+			return EPSG{
+				Code:       code,
+				Name:       fmt.Sprintf(s.name, zone),
+				Area:       s.area(zone),
+				Definition: fmt.Sprintf(s.defn, zone),
+			}, nil
+		}
+	}
+	i := sort.Search(len(epsgTable), func(i int) bool { return epsgTable[i].code >= code })
+	if i < len(epsgTable) && epsgTable[i].code == code {
+		r := epsgTable[i]
+		return EPSG{r.code, r.name, epsgAreas[r.area], r.deprecated, r.defn}, nil
+	}
+	return EPSG{}, ErrUnknownCRS
+}
+
+// parseEPSGCode reads an "EPSG:1234" style code, ignoring case.
+func parseEPSGCode(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 5 || !strings.EqualFold(s[:5], "epsg:") {
+		return 0, false
+	}
+	code, err := strconv.Atoi(s[5:])
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// expandInit merges the definition named by +init into parms, with the
+// parameters already in parms taking precedence, as with PROJ's init files.
+func (parms paramset) expandInit() error {
+	name, ok := parms["init"]
+	if !ok {
+		return nil
+	}
+	code, ok := parseEPSGCode(name)
+	if !ok {
+		return ErrUnknownCRS
+	}
+	e, err := LookupEPSG(code)
+	if err != nil {
+		return err
+	}
+	for key, val := range parseParams(e.Definition) {
+		if _, ok := parms[key]; !ok {
+			parms[key] = val
+		}
+	}
+	delete(parms, "init")
+	return nil
+}
+
+// utmArea describes a UTM zone's extent.
+func utmArea(zone int, hemisphere string) string {
+	return fmt.Sprintf("Between %s and %s, %s.", meridian(zone*6-186), meridian(zone*6-180), hemisphere)
+}
+
+func meridian(lng int) string {
+	switch {
+	case lng < 0:
+		return fmt.Sprintf("%d°W", -lng)
+	case lng > 0:
+		return fmt.Sprintf("%d°E", lng)
+	}
+	return "0°E"
+}
+
+// epsgSeries are runs of codes that differ only by their zone.
+var epsgSeries = []struct {
+	first, last, zone int
+	name, defn        string
+	area              func(zone int) string
+}{
+	{32601, 32660, 1, "WGS 84 / UTM zone %dN", "+proj=utm +zone=%d +datum=WGS84 +units=m",
+		func(zone int) string {
+			return utmArea(zone, "northern hemisphere between equator and 84°N")
+		}},
+	{32701, 32760, 1, "WGS 84 / UTM zone %dS", "+proj=utm +zone=%d +south +datum=WGS84 +units=m",
+		func(zone int) string {
+			return utmArea(zone, "southern hemisphere between 80°S and equator")
+		}},
+	{26701, 26722, 1, "NAD27 / UTM zone %dN", "+proj=utm +zone=%d +datum=NAD27 +units=m",
+		func(zone int) string {
+			return utmArea(zone, "North America")
+		}},
+	{26901, 26923, 1, "NAD83 / UTM zone %dN", "+proj=utm +zone=%d +datum=NAD83 +units=m",
+		func(zone int) string {
+			return utmArea(zone, "North America")
+		}},
+	{25828, 25838, 28, "ETRS89 / UTM zone %dN", "+proj=utm +zone=%d +ellps=GRS80 +towgs84=0,0,0 +units=m",
+		func(zone int) string {
+			return utmArea(zone, "Europe")
+		}},
+	{28348, 28358, 48, "GDA94 / MGA zone %d", "+proj=utm +zone=%d +south +ellps=GRS80 +towgs84=0,0,0 +units=m",
+		func(zone int) string {
+			return utmArea(zone, "Australia")
+		}},
+	{31977, 31985, 17, "SIRGAS 2000 / UTM zone %dS", "+proj=utm +zone=%d +south +ellps=GRS80 +towgs84=0,0,0 +units=m",
+		func(zone int) string {
+			return utmArea(zone, "Latin America, southern hemisphere")
+		}},
+}
+
+// epsgRow is a CRS in the generated table.
+type epsgRow struct {
+	code       int
+	name       string
+	area       int // index into epsgAreas
+	deprecated bool
+	defn       string
+}
+
+// epsgTable is every EPSG CRS in PROJ's proj.db whose method we implement,
+// sorted by code, and epsgAreas the areas of use it refers to.  gen_epsg.go
+// fills them in from epsg_table.go; without that file they're empty.  The
+// codes picked by hand take precedence since proj.db leaves out the datum
+// shifts to WGS 84 they carry.
+var (
+	epsgTable []epsgRow
+	epsgAreas []string
+)
+
+// epsgCodes are the codes picked by hand, limited to the projections we
+// support.  The package doc lists them, so keep it up to date when adding
+// one.
+var epsgCodes = map[int]EPSG{
+	// geographic
+	4326: {4326, "WGS 84", "World.", false,
+		"+proj=longlat +datum=WGS84"},
+	4269: {4269, "NAD83", "North America - onshore and offshore: Canada, Puerto Rico, United States (USA), US Virgin Islands, British Virgin Islands.", false,
+		"+proj=longlat +datum=NAD83"},
+	4267: {4267, "NAD27", "North and central America: Antigua and Barbuda, Bahamas, Belize, Canada, Cuba, Mexico, United States (USA) and others.", false,
+		"+proj=longlat +datum=NAD27"},
+	4258: {4258, "ETRS89", "Europe - onshore and offshore.", false,
+		"+proj=longlat +ellps=GRS80 +towgs84=0,0,0"},
+	4277: {4277, "OSGB 1936", "United Kingdom (UK) - offshore to boundary of UKCS within 49°45'N to 61°N and 9°W to 2°E; onshore Great Britain (England, Wales and Scotland) and Isle of Man.", false,
+		"+proj=longlat +ellps=airy +towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489"},
+	4230: {4230, "ED50", "Europe - west: Andorra, Belgium, Denmark, Faroe Islands, France, Germany, Gibraltar, Greece, Italy, Luxembourg, Netherlands, Norway, Portugal, Spain, Sweden, Switzerland and others.", false,
+		"+proj=longlat +ellps=intl +towgs84=-87,-98,-121"},
+	4314: {4314, "DHDN", "Germany - West Germany all states prior to 1990.", false,
+		"+proj=longlat +datum=potsdam"},
+	4322: {4322, "WGS 72", "World.", false,
+		"+proj=longlat +ellps=WGS72 +towgs84=0,0,4.5,0,0,0.554,0.2263"},
+	4283: {4283, "GDA94", "Australia including Lord Howe Island, Macquarie Island, Ashmore and Cartier Islands, Christmas Island, Cocos (Keeling) Islands, Norfolk Island.", false,
+		"+proj=longlat +ellps=GRS80 +towgs84=0,0,0"},
+	4167: {4167, "NZGD2000", "New Zealand - onshore and offshore.", false,
+		"+proj=longlat +ellps=GRS80 +towgs84=0,0,0"},
+	4272: {4272, "NZGD49", "New Zealand - North Island, South Island, Stewart Island - onshore.", false,
+		"+proj=longlat +datum=nzgd49"},
+	4617: {4617, "NAD83(CSRS)", "Canada - onshore and offshore.", false,
+		"+proj=longlat +ellps=GRS80 +towgs84=0,0,0"},
+	4674: {4674, "SIRGAS 2000", "Latin America - Central America and South America - onshore and offshore.", false,
+		"+proj=longlat +ellps=GRS80 +towgs84=0,0,0"},
+	4121: {4121, "GGRS87", "Greece - onshore.", false,
+		"+proj=longlat +datum=GGRS87"},
+
+	// geocentric
+	4978: {4978, "WGS 84", "World.", false,
+		"+proj=geocent +datum=WGS84 +units=m"},
+
+	// world
+	3857: {3857, "WGS 84 / Pseudo-Mercator", "World between 85.06°S and 85.06°N.", false,
+		"+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null +wktext"},
+	3785: {3785, "Popular Visualisation CRS / Mercator", "World between 85.06°S and 85.06°N.", true,
+		"+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null +wktext"},
+	3395: {3395, "WGS 84 / World Mercator", "World between 80°S and 84°N.", false,
+		"+proj=merc +lon_0=0 +k=1 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	4087: {4087, "WGS 84 / World Equidistant Cylindrical", "World.", false,
+		"+proj=eqc +lat_ts=0 +lat_0=0 +lon_0=0 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	32662: {32662, "WGS 84 / Plate Carree", "World.", true,
+		"+proj=eqc +lat_ts=0 +lat_0=0 +lon_0=0 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+
+	// polar
+	3031: {3031, "WGS 84 / Antarctic Polar Stereographic", "Antarctica.", false,
+		"+proj=stere +lat_0=-90 +lat_ts=-71 +lon_0=0 +k=1 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3413: {3413, "WGS 84 / NSIDC Sea Ice Polar Stereographic North", "Northern hemisphere - north of 60°N onshore and offshore, including Arctic.", false,
+		"+proj=stere +lat_0=90 +lat_ts=70 +lon_0=-45 +k=1 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3976: {3976, "WGS 84 / NSIDC Sea Ice Polar Stereographic South", "Southern hemisphere - south of 60°S onshore and offshore - Antarctica.", false,
+		"+proj=stere +lat_0=-90 +lat_ts=-70 +lon_0=0 +k=1 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3995: {3995, "WGS 84 / Arctic Polar Stereographic", "Northern hemisphere - north of 60°N onshore and offshore, including Arctic.", false,
+		"+proj=stere +lat_0=90 +lat_ts=71 +lon_0=0 +k=1 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3571: {3571, "WGS 84 / North Pole LAEA Bering Sea", "Northern hemisphere - north of 45°N, including Arctic.", false,
+		"+proj=laea +lat_0=90 +lon_0=180 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3572: {3572, "WGS 84 / North Pole LAEA Alaska", "Northern hemisphere - north of 45°N, including Arctic.", false,
+		"+proj=laea +lat_0=90 +lon_0=-150 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3573: {3573, "WGS 84 / North Pole LAEA Canada", "Northern hemisphere - north of 45°N, including Arctic.", false,
+		"+proj=laea +lat_0=90 +lon_0=-100 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3574: {3574, "WGS 84 / North Pole LAEA Atlantic", "Northern hemisphere - north of 45°N, including Arctic.", false,
+		"+proj=laea +lat_0=90 +lon_0=-40 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3575: {3575, "WGS 84 / North Pole LAEA Europe", "Northern hemisphere - north of 45°N, including Arctic.", false,
+		"+proj=laea +lat_0=90 +lon_0=10 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+	3576: {3576, "WGS 84 / North Pole LAEA Russia", "Northern hemisphere - north of 45°N, including Arctic.", false,
+		"+proj=laea +lat_0=90 +lon_0=90 +x_0=0 +y_0=0 +datum=WGS84 +units=m"},
+
+	// Europe
+	27700: {27700, "OSGB 1936 / British National Grid", "United Kingdom (UK) - offshore to boundary of UKCS within 49°45'N to 61°N and 9°W to 2°E; onshore Great Britain (England, Wales and Scotland) and Isle of Man.", false,
+		"+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy +towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489 +units=m"},
+	3035: {3035, "ETRS89-extended / LAEA Europe", "Europe - European Union (EU) countries and candidates.", false,
+		"+proj=laea +lat_0=52 +lon_0=10 +x_0=4321000 +y_0=3210000 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	3034: {3034, "ETRS89-extended / LCC Europe", "Europe - European Union (EU) countries and candidates.", false,
+		"+proj=lcc +lat_1=35 +lat_2=65 +lat_0=52 +lon_0=10 +x_0=4000000 +y_0=2800000 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	2154: {2154, "RGF93 v1 / Lambert-93", "France - onshore and offshore, mainland and Corsica.", false,
+		"+proj=lcc +lat_1=49 +lat_2=44 +lat_0=46.5 +lon_0=3 +x_0=700000 +y_0=6600000 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	28992: {28992, "Amersfoort / RD New", "Netherlands - onshore, including Waddenzee, Dutch Wadden Islands and 12-mile offshore coastal zone.", false,
+		"+proj=sterea +lat_0=52.15616055555555 +lon_0=5.38763888888889 +k=0.9999079 +x_0=155000 +y_0=463000 +ellps=bessel +towgs84=565.417,50.3319,465.552,-0.398957,0.343988,-1.8774,4.0725 +units=m"},
+	31370: {31370, "BD72 / Belgian Lambert 72", "Belgium - onshore.", false,
+		"+proj=lcc +lat_1=51.16666723333333 +lat_2=49.8333339 +lat_0=90 +lon_0=4.367486666666666 +x_0=150000.013 +y_0=5400088.438 +ellps=intl +towgs84=-106.869,52.2978,-103.724,0.3366,-0.457,1.8422,-1.2747 +units=m"},
+	31467: {31467, "DHDN / 3-degree Gauss-Kruger zone 3", "Germany - former West Germany onshore between 7°30'E and 10°30'E.", false,
+		"+proj=tmerc +lat_0=0 +lon_0=9 +k=1 +x_0=3500000 +y_0=0 +datum=potsdam +units=m"},
+	3006: {3006, "SWEREF99 TM", "Sweden - onshore and offshore.", false,
+		"+proj=utm +zone=33 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	3067: {3067, "ETRS89 / TM35FIN(E,N)", "Finland - onshore and offshore.", false,
+		"+proj=utm +zone=35 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	2100: {2100, "GGRS87 / Greek Grid", "Greece - onshore.", false,
+		"+proj=tmerc +lat_0=0 +lon_0=24 +k=0.9996 +x_0=500000 +y_0=0 +datum=GGRS87 +units=m"},
+	2180: {2180, "ETRF2000-PL / CS92", "Poland - onshore and offshore.", false,
+		"+proj=tmerc +lat_0=0 +lon_0=19 +k=0.9993 +x_0=500000 +y_0=-5300000 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	3003: {3003, "Monte Mario / Italy zone 1", "Italy - onshore and offshore - west of 12°E.", false,
+		"+proj=tmerc +lat_0=0 +lon_0=9 +k=0.9996 +x_0=1500000 +y_0=0 +ellps=intl +towgs84=-104.1,-49.1,-9.9,0.971,-2.917,0.714,-11.68 +units=m"},
+
+	// North America
+	5070: {5070, "NAD83 / Conus Albers", "United States (USA) - CONUS onshore.", false,
+		"+proj=aea +lat_1=29.5 +lat_2=45.5 +lat_0=23 +lon_0=-96 +x_0=0 +y_0=0 +datum=NAD83 +units=m"},
+	3338: {3338, "NAD83 / Alaska Albers", "United States (USA) - Alaska.", false,
+		"+proj=aea +lat_1=55 +lat_2=65 +lat_0=50 +lon_0=-154 +x_0=0 +y_0=0 +datum=NAD83 +units=m"},
+	2163: {2163, "US National Atlas Equal Area", "United States (USA) - onshore and offshore.", true,
+		"+proj=laea +lat_0=45 +lon_0=-100 +x_0=0 +y_0=0 +a=6370997 +b=6370997 +units=m"},
+	26986: {26986, "NAD83 / Massachusetts Mainland", "United States (USA) - Massachusetts onshore - counties of Barnstable; Berkshire; Bristol; Essex; Franklin; Hampden; Hampshire; Middlesex; Norfolk; Plymouth; Suffolk; Worcester.", false,
+		"+proj=lcc +lat_1=42.68333333333333 +lat_2=41.71666666666667 +lat_0=41 +lon_0=-71.5 +x_0=200000 +y_0=750000 +datum=NAD83 +units=m"},
+	2227: {2227, "NAD83 / California zone 3 (ftUS)", "United States (USA) - California - counties Alameda; Calaveras; Contra Costa; Madera; Marin; Mariposa; Merced; Mono; San Francisco; San Joaquin; San Mateo; Santa Clara; Santa Cruz; Stanislaus; Tuolumne.", false,
+		"+proj=lcc +lat_1=38.43333333333333 +lat_2=37.06666666666667 +lat_0=36.5 +lon_0=-120.5 +x_0=2000000.0001016 +y_0=500000.0001016001 +datum=NAD83 +units=us-ft"},
+	2263: {2263, "NAD83 / New York Long Island (ftUS)", "United States (USA) - New York - counties of Bronx; Kings; Nassau; New York; Queens; Richmond; Suffolk.", false,
+		"+proj=lcc +lat_1=41.03333333333333 +lat_2=40.66666666666666 +lat_0=40.16666666666666 +lon_0=-74 +x_0=300000.0000000001 +y_0=0 +datum=NAD83 +units=us-ft"},
+	3347: {3347, "NAD83 / Statistics Canada Lambert", "Canada - onshore and offshore.", false,
+		"+proj=lcc +lat_1=49 +lat_2=77 +lat_0=63.390675 +lon_0=-91.86666666666666 +x_0=6200000 +y_0=3000000 +datum=NAD83 +units=m"},
+	3348: {3348, "NAD83(CSRS) / Statistics Canada Lambert", "Canada - onshore and offshore.", false,
+		"+proj=lcc +lat_1=49 +lat_2=77 +lat_0=63.390675 +lon_0=-91.86666666666666 +x_0=6200000 +y_0=3000000 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+
+	// Oceania
+	3577: {3577, "GDA94 / Australian Albers", "Australia - Australian Capital Territory; New South Wales; Northern Territory; Queensland; South Australia; Tasmania; Western Australia; Victoria.", false,
+		"+proj=aea +lat_1=-18 +lat_2=-36 +lat_0=0 +lon_0=132 +x_0=0 +y_0=0 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	3112: {3112, "GDA94 / Geoscience Australia Lambert", "Australia - Australian Capital Territory; New South Wales; Northern Territory; Queensland; South Australia; Tasmania; Western Australia; Victoria.", false,
+		"+proj=lcc +lat_1=-18 +lat_2=-36 +lat_0=0 +lon_0=134 +x_0=0 +y_0=0 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+	2193: {2193, "NZGD2000 / New Zealand Transverse Mercator 2000", "New Zealand - North Island, South Island, Stewart Island - onshore.", false,
+		"+proj=tmerc +lat_0=0 +lon_0=173 +k=0.9996 +x_0=1600000 +y_0=10000000 +ellps=GRS80 +towgs84=0,0,0 +units=m"},
+}
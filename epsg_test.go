@@ -0,0 +1,194 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"errors"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEPSGRegistry(t *testing.T) {
+	var codes []int
+	for code := range epsgCodes {
+		codes = append(codes, code)
+	}
+	for _, s := range epsgSeries {
+		for code := s.first; code <= s.last; code++ {
+			codes = append(codes, code)
+		}
+	}
+	for i, r := range epsgTable {
+		if i > 0 && r.code <= epsgTable[i-1].code {
+			t.Errorf("%d: the generated table isn't sorted", r.code)
+		}
+		codes = append(codes, r.code)
+	}
+
+	for _, code := range codes {
+		e, err := LookupEPSG(code)
+		if err != nil {
+			t.Errorf("%d: %v", code, err)
+			continue
+		}
+		if e.Code != code || e.Name == "" || e.Area == "" {
+			t.Errorf("%d: incomplete entry %+v", code, e)
+		}
+		if _, err := NewProjection(e.Definition); err != nil {
+			t.Errorf("%d: %s: %v", code, e.Definition, err)
+		}
+	}
+}
+
+// the package doc lists every code we carry, and nothing else
+func TestEPSGDocumented(t *testing.T) {
+	src, err := os.ReadFile("doc.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(src)
+	doc = doc[strings.Index(doc, "# EPSG codes"):strings.Index(doc, "Any code in neither part")]
+	// without the names, like SIRGAS 2000
+	doc = regexp.MustCompile(`\([^)]*\)`).ReplaceAllString(doc, "")
+	documented := make(map[int]bool)
+	for _, m := range regexp.MustCompile(`\b(\d{4,5})(?:-(\d{4,5}))?\b`).FindAllStringSubmatch(doc, -1) {
+		first, _ := strconv.Atoi(m[1])
+		last := first
+		if m[2] != "" {
+			last, _ = strconv.Atoi(m[2])
+		}
+		for code := first; code <= last; code++ {
+			documented[code] = true
+		}
+	}
+
+	carried := make(map[int]bool)
+	for code := range epsgCodes {
+		carried[code] = true
+	}
+	for _, s := range epsgSeries {
+		for code := s.first; code <= s.last; code++ {
+			carried[code] = true
+		}
+	}
+	for code := range carried {
+		if !documented[code] {
+			t.Errorf("EPSG:%d isn't in the package doc", code)
+		}
+	}
+	for code := range documented {
+		if !carried[code] {
+			t.Errorf("EPSG:%d is in the package doc, but we don't have it", code)
+		}
+	}
+}
+
+func TestLookupEPSG(t *testing.T) {
+	var tests = []struct {
+		code       int
+		name, area string
+		deprecated bool
+	}{
+		{4326, "WGS 84", "World.", false},
+		{32633, "WGS 84 / UTM zone 33N", "Between 12°E and 18°E, northern hemisphere between equator and 84°N.", false},
+		{32719, "WGS 84 / UTM zone 19S", "Between 72°W and 66°W, southern hemisphere between 80°S and equator.", false},
+		{26910, "NAD83 / UTM zone 10N", "Between 126°W and 120°W, North America.", false},
+		{3785, "Popular Visualisation CRS / Mercator", "World between 85.06°S and 85.06°N.", true},
+	}
+	for _, tt := range tests {
+		e, err := LookupEPSG(tt.code)
+		if err != nil {
+			t.Errorf("%d: %v", tt.code, err)
+			continue
+		}
+		if e.Name != tt.name || e.Area != tt.area || e.Deprecated != tt.deprecated {
+			t.Errorf("%d: got %+v", tt.code, e)
+		}
+	}
+
+	for _, code := range []int{0, 4325, 32661, 32700} {
+		if _, err := LookupEPSG(code); !errors.Is(err, ErrUnknownCRS) {
+			t.Errorf("%d: expected ErrUnknownCRS, got %v", code, err)
+		}
+	}
+}
+
+// the generated table fills the gaps between the codes picked by hand
+func TestLookupEPSGTable(t *testing.T) {
+	table, areas := epsgTable, epsgAreas
+	defer func() { epsgTable, epsgAreas = table, areas }()
+	epsgAreas = []string{"Liechtenstein; Switzerland.", "World."}
+	epsgTable = []epsgRow{
+		{2056, "CH1903+ / LV95", 0, false,
+			"+proj=somerc +lat_0=46.9524055555556 +lon_0=7.43958333333333 +k_0=1 +x_0=2600000 +y_0=1200000 +ellps=bessel +units=m"},
+		{4326, "WGS 84", 1, false, "+proj=longlat +ellps=WGS84"},
+	}
+
+	e, err := LookupEPSG(2056)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != 2056 || e.Name != "CH1903+ / LV95" || e.Area != "Liechtenstein; Switzerland." || e.Deprecated {
+		t.Errorf("2056: got %+v", e)
+	}
+	if _, err := NewProjection("EPSG:2056"); err != nil {
+		t.Errorf("EPSG:2056: %v", err)
+	}
+	// the hand-picked definition, with its datum, wins
+	if e, _ := LookupEPSG(4326); e.Definition != "+proj=longlat +datum=WGS84" {
+		t.Errorf("4326: got %s", e.Definition)
+	}
+	for _, code := range []int{2055, 2057, 4327} {
+		if _, err := LookupEPSG(code); !errors.Is(err, ErrUnknownCRS) {
+			t.Errorf("%d: expected ErrUnknownCRS, got %v", code, err)
+		}
+	}
+}
+
+func TestNewProjectionEPSG(t *testing.T) {
+	var tests = []struct {
+		defn, exp string
+	}{
+		{"EPSG:4326", "+proj=longlat +datum=WGS84"},
+		{"epsg:27700", "+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy +towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489 +units=m"},
+		{"+init=epsg:3857", "+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null"},
+		{" EPSG:32633 ", "+proj=utm +zone=33 +datum=WGS84"},
+		// what's given overrides the init file
+		{"+init=epsg:32633 +zone=34", "+proj=utm +zone=34 +datum=WGS84"},
+		{"+init=EPSG:4326 +lon_0=10", "+proj=longlat +datum=WGS84 +lon_0=10"},
+	}
+	for _, tt := range tests {
+		pj, err := NewProjection(tt.defn)
+		if err != nil {
+			t.Errorf("%s: %v", tt.defn, err)
+			continue
+		}
+		exp, err := NewProjection(tt.exp)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.exp, err)
+		}
+		if definition(pj) != definition(exp) {
+			t.Errorf("%s: got %s, want %s", tt.defn, definition(pj), definition(exp))
+		}
+	}
+
+	pj, err := NewProjection("EPSG:3857")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, _, _ := pj.Forward(math.Pi, 0); !within(20037508.342789244, x, 1e-6) {
+		t.Errorf("EPSG:3857: expected x of 20037508.342789244, got %f", x)
+	}
+
+	for _, defn := range []string{"EPSG:1", "+init=esri:102003", "+init=nad27:3001"} {
+		if _, err := NewProjection(defn); !errors.Is(err, ErrUnknownCRS) {
+			t.Errorf("%s: expected ErrUnknownCRS, got %v", defn, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is one of PROJ's error codes.  They come in three categories,
+// ErrCodeInvalidOp for definitions that don't make sense,
+// ErrCodeCoordTransfm for coordinates that can't be projected and
+// ErrCodeOther for everything else, and errors.Is matches a
+// ProjectionError against its category as well as its own code.
+type ErrorCode int
+
+// https://github.com/OSGeo/PROJ/blob/9.4/src/proj.h
+const (
+	ErrCodeInvalidOp             ErrorCode = 1024
+	ErrCodeWrongSyntax           ErrorCode = ErrCodeInvalidOp + 1
+	ErrCodeMissingArg            ErrorCode = ErrCodeInvalidOp + 2
+	ErrCodeIllegalArgValue       ErrorCode = ErrCodeInvalidOp + 3
+	ErrCodeMutuallyExclusiveArgs ErrorCode = ErrCodeInvalidOp + 4
+	ErrCodeFileNotFoundOrInvalid ErrorCode = ErrCodeInvalidOp + 5
+
+	ErrCodeCoordTransfm            ErrorCode = 2048
+	ErrCodeInvalidCoord            ErrorCode = ErrCodeCoordTransfm + 1
+	ErrCodeOutsideProjectionDomain ErrorCode = ErrCodeCoordTransfm + 2
+	ErrCodeNoOperation             ErrorCode = ErrCodeCoordTransfm + 3
+	ErrCodeOutsideGrid             ErrorCode = ErrCodeCoordTransfm + 4
+	ErrCodeGridAtNodata            ErrorCode = ErrCodeCoordTransfm + 5
+	ErrCodeNoConvergence           ErrorCode = ErrCodeCoordTransfm + 6
+
+	ErrCodeOther       ErrorCode = 4096
+	ErrCodeAPIMisuse   ErrorCode = ErrCodeOther + 1
+	ErrCodeNoInverseOp ErrorCode = ErrCodeOther + 2
+)
+
+// the messages from proj_context_errno_string
+var errorCodeText = map[ErrorCode]string{
+	ErrCodeInvalidOp:               "Unspecified error related to invalid operation",
+	ErrCodeWrongSyntax:             "Invalid PROJ string syntax",
+	ErrCodeMissingArg:              "Missing argument",
+	ErrCodeIllegalArgValue:         "Invalid value for an argument",
+	ErrCodeMutuallyExclusiveArgs:   "Mutually exclusive arguments",
+	ErrCodeFileNotFoundOrInvalid:   "File not found or invalid",
+	ErrCodeCoordTransfm:            "Unspecified error related to coordinate transformation",
+	ErrCodeInvalidCoord:            "Invalid coordinate",
+	ErrCodeOutsideProjectionDomain: "Point outside of projection domain",
+	ErrCodeNoOperation:             "No operation matching criteria found for coordinate",
+	ErrCodeOutsideGrid:             "Point outside of coordinate transformation grid",
+	ErrCodeGridAtNodata:            "Coordinate to transform falls on a grid cell that evaluates to nodata",
+	ErrCodeNoConvergence:           "Iterative method fails to converge on coordinate to transform",
+	ErrCodeOther:                   "Unknown error",
+	ErrCodeAPIMisuse:               "API misuse",
+	ErrCodeNoInverseOp:             "No inverse operation",
+}
+
+func (c ErrorCode) Error() string {
+	if text, ok := errorCodeText[c]; ok {
+		return text
+	}
+	return fmt.Sprintf("Unknown error (code %d)", int(c))
+}
+
+// category is ErrCodeInvalidOp, ErrCodeCoordTransfm or ErrCodeOther.
+func (c ErrorCode) category() ErrorCode {
+	return c &^ 1023
+}
+
+// errorCodes are the codes PROJ would give for our sentinel errors.
+var errorCodes = map[error]ErrorCode{
+	ErrUnsupportedProj:    ErrCodeIllegalArgValue,
+	ErrUnknownDatum:       ErrCodeIllegalArgValue,
+	ErrInvalidParam:       ErrCodeIllegalArgValue,
+	ErrUnknownCRS:         ErrCodeIllegalArgValue,
+	ErrUnknownUnit:        ErrCodeIllegalArgValue,
+	ErrInvalidWKT:         ErrCodeWrongSyntax,
+	ErrInvalidPROJJSON:    ErrCodeWrongSyntax,
+	ErrGridNotFound:       ErrCodeFileNotFoundOrInvalid,
+	ErrGridFormat:         ErrCodeFileNotFoundOrInvalid,
+	ErrInvalidCoord:       ErrCodeInvalidCoord,
+	ErrToleranceCondition: ErrCodeOutsideProjectionDomain,
+	ErrPointNotVisible:    ErrCodeOutsideProjectionDomain,
+	ErrOutsideGrid:        ErrCodeOutsideGrid,
+	ErrNoConvergence:      ErrCodeNoConvergence,
+	ErrGeocentric:         ErrCodeAPIMisuse,
+}
+
+// ProjectionError says what went wrong making a projection or projecting
+// a coordinate with one.  errors.Is sees through it to the Err* value
+// that caused it, and matches its Code and the Code's category.
+type ProjectionError struct {
+	Code ErrorCode
+	// Proj is the +proj of the projection, if there is one
+	Proj string
+	// Param is the parameter that's wrong, for the ErrCodeInvalidOp codes,
+	// or the argument of the function Op, for ErrCodeAPIMisuse
+	Param string
+	// Op is the calculation that failed, like phi2, if it's not just
+	// the projection's, or the function that was called wrongly
+	Op string
+	// X and Y are the coordinate that the projection couldn't project,
+	// for the ErrCodeCoordTransfm codes: lng/lat in radians for Forward,
+	// or x/y for Inverse
+	X, Y float64
+	Err  error
+}
+
+func (e *ProjectionError) Error() string {
+	var b strings.Builder
+	if e.Proj != "" {
+		b.WriteString(e.Proj + ": ")
+	}
+	if e.Code == ErrCodeAPIMisuse {
+		if e.Op != "" {
+			b.WriteString(e.Op + ": ")
+		}
+		if e.Param != "" {
+			b.WriteString(e.Param + ": ")
+		}
+	} else {
+		if e.Param != "" {
+			b.WriteString("+" + e.Param + ": ")
+		}
+		if e.Op != "" {
+			b.WriteString(e.Op + ": ")
+		}
+	}
+	if e.Proj != "" && e.Code.category() == ErrCodeCoordTransfm {
+		fmt.Fprintf(&b, "(%g, %g): ", e.X, e.Y)
+	}
+	if e.Err != nil {
+		b.WriteString(e.Err.Error())
+	} else {
+		b.WriteString(e.Code.Error())
+	}
+	return b.String()
+}
+
+func (e *ProjectionError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches an ErrorCode that's either e's code or its category.
+func (e *ProjectionError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && (code == e.Code || code == e.Code.category())
+}
+
+// newError describes err, one of the Err* values or an error that's
+// already a ProjectionError, in which case it's copied.
+func newError(err error) *ProjectionError {
+	if pe, ok := err.(*ProjectionError); ok {
+		cp := *pe
+		return &cp
+	}
+	code, ok := errorCodes[err]
+	if !ok {
+		code = ErrCodeOther
+	}
+	return &ProjectionError{Code: code, Err: err}
+}
+
+// paramError says that param is why err happened.
+func paramError(param string, err error) *ProjectionError {
+	pe := newError(err)
+	if pe.Param == "" {
+		pe.Param = param
+	}
+	return pe
+}
+
+// argError says that the argument arg of the function fn is why err
+// happened: it was called wrongly, rather than with a bad projection.
+func argError(fn, arg string, err error) *ProjectionError {
+	pe := newError(err)
+	pe.Code, pe.Op, pe.Param = ErrCodeAPIMisuse, fn, arg
+	return pe
+}
+
+// noConvergence says that the iteration in op didn't settle down.
+func noConvergence(op string) *ProjectionError {
+	return &ProjectionError{Code: ErrCodeNoConvergence, Op: op, Err: ErrNoConvergence}
+}
+
+// paramError says that param of p is why err happened.
+func (p *pj) paramError(param string, err error) error {
+	pe := paramError(param, err)
+	pe.Proj = p.proj
+	return pe
+}
+
+// coordError says that err happened projecting x/y with p.
+func (p *pj) coordError(err error, x, y float64) error {
+	pe := newError(err)
+	pe.Proj, pe.X, pe.Y = p.proj, x, y
+	return pe
+}
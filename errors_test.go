@@ -0,0 +1,159 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestProjectionErrorParams(t *testing.T) {
+	for _, test := range []struct {
+		defn  string
+		code  ErrorCode
+		proj  string
+		param string
+		err   error
+	}{
+		{"+ellps=WGS84", ErrCodeMissingArg, "", "proj", ErrUnsupportedProj},
+		{"+proj=robin +ellps=WGS84", ErrCodeIllegalArgValue, "robin", "proj", ErrUnsupportedProj},
+		{"+proj=merc +ellps=WGS84 +axis=enx", ErrCodeIllegalArgValue, "merc", "axis", ErrInvalidParam},
+		{"+proj=merc +ellps=WGS84 +k_0=0", ErrCodeIllegalArgValue, "merc", "k_0", ErrInvalidParam},
+		{"+proj=merc +ellps=WGS84 +units=furlong", ErrCodeIllegalArgValue, "merc", "units", ErrUnknownUnit},
+		{"+proj=merc +ellps=WGS84 +vto_meter=0", ErrCodeIllegalArgValue, "merc", "vto_meter", ErrInvalidParam},
+		{"+proj=merc +ellps=WGS84 +towgs84=1,2", ErrCodeIllegalArgValue, "merc", "towgs84", ErrInvalidParam},
+		{"+proj=longlat +ellps=WGS84 +pm=atlantis", ErrCodeIllegalArgValue, "longlat", "pm", ErrInvalidParam},
+		{"+proj=lcc +lat_1=30 +lat_2=-30 +ellps=WGS84", ErrCodeIllegalArgValue, "lcc", "lat_1", ErrInvalidParam},
+		{"+proj=aea +lat_1=30 +lat_2=-30 +ellps=WGS84", ErrCodeIllegalArgValue, "aea", "lat_2", ErrInvalidParam},
+		{"+proj=eqc +lat_ts=90 +R=6400000", ErrCodeIllegalArgValue, "eqc", "lat_ts", ErrInvalidParam},
+		{"+proj=utm +zone=61 +ellps=WGS84", ErrCodeIllegalArgValue, "utm", "zone", ErrInvalidParam},
+		{"+proj=laea +lat_0=91 +ellps=WGS84", ErrCodeIllegalArgValue, "laea", "lat_0", ErrInvalidParam},
+		{"EPSG:1", ErrCodeIllegalArgValue, "", "init", ErrUnknownCRS},
+	} {
+		_, err := NewProjection(test.defn)
+		var pe *ProjectionError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: expected a ProjectionError, got %v", test.defn, err)
+			continue
+		}
+		if pe.Code != test.code || pe.Proj != test.proj || pe.Param != test.param {
+			t.Errorf("%s: expected %d %q %q, got %d %q %q", test.defn,
+				test.code, test.proj, test.param, pe.Code, pe.Proj, pe.Param)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.defn, test.err, err)
+		}
+		if !errors.Is(err, test.code) || !errors.Is(err, ErrCodeInvalidOp) || errors.Is(err, ErrCodeCoordTransfm) {
+			t.Errorf("%s: %v doesn't match its code", test.defn, err)
+		}
+	}
+}
+
+func TestProjectionErrorCoords(t *testing.T) {
+	ortho, _ := NewProjection("+proj=ortho +lat_0=40 +lon_0=-100 +R=6400000")
+	merc, _ := NewProjection("+proj=merc +ellps=WGS84")
+	stere, _ := NewProjection("+proj=stere +lat_0=90 +R=6400000")
+	geocent, _ := NewProjection("+proj=geocent +datum=WGS84")
+	for _, test := range []struct {
+		name string
+		fn   func() (float64, float64, error)
+		x, y float64
+		code ErrorCode
+		err  error
+	}{
+		{"not visible", func() (float64, float64, error) { return ortho.Forward(80*d2r, -10*d2r) },
+			80 * d2r, -10 * d2r, ErrCodeOutsideProjectionDomain, ErrPointNotVisible},
+		{"off the disc", func() (float64, float64, error) { return ortho.Inverse(7e6, 0) },
+			7e6, 0, ErrCodeOutsideProjectionDomain, ErrPointNotVisible},
+		{"past the pole", func() (float64, float64, error) { return merc.Forward(0, 2) },
+			0, 2, ErrCodeInvalidCoord, ErrInvalidCoord},
+		{"hugeVal", func() (float64, float64, error) { return merc.Inverse(hugeVal, 0) },
+			hugeVal, 0, ErrCodeInvalidCoord, ErrInvalidCoord},
+		{"opposite pole", func() (float64, float64, error) { return stere.Forward(0, -half_pi) },
+			0, -half_pi, ErrCodeOutsideProjectionDomain, ErrToleranceCondition},
+	} {
+		_, _, err := test.fn()
+		var pe *ProjectionError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: expected a ProjectionError, got %v", test.name, err)
+			continue
+		}
+		if pe.Code != test.code || pe.X != test.x || pe.Y != test.y {
+			t.Errorf("%s: expected %d at (%g, %g), got %d at (%g, %g)", test.name,
+				test.code, test.x, test.y, pe.Code, pe.X, pe.Y)
+		}
+		if !errors.Is(err, test.err) || !errors.Is(err, ErrCodeCoordTransfm) || errors.Is(err, ErrCodeInvalidOp) {
+			t.Errorf("%s: %v doesn't match its code", test.name, err)
+		}
+	}
+
+	_, _, err := geocent.Forward(0, 0)
+	if !errors.Is(err, ErrGeocentric) || !errors.Is(err, ErrCodeAPIMisuse) || !errors.Is(err, ErrCodeOther) {
+		t.Errorf("expected ErrGeocentric as API misuse, got %v", err)
+	}
+}
+
+func TestArgError(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +ellps=WGS84")
+	geocent, _ := NewProjection("+proj=geocent +ellps=WGS84")
+	for _, test := range []struct {
+		err       error
+		fn, param string
+		cause     error
+	}{
+		{Transform(wgs84, wgs84, make([]float64, 2), make([]float64, 1), nil), "Transform", "y", ErrInvalidParam},
+		{Transform(wgs84, wgs84, make([]float64, 2), make([]float64, 2), make([]float64, 1)), "Transform", "z", ErrInvalidParam},
+		{Transform(wgs84, geocent, make([]float64, 2), make([]float64, 2), nil), "Transform", "z", ErrGeocentric},
+		{TransformParallel(wgs84, wgs84, make([]float64, 2), make([]float64, 1), nil, 0), "TransformParallel", "y", ErrInvalidParam},
+		{InverseSlice(wgs84, make([]float64, 2), nil), "InverseSlice", "y", ErrInvalidParam},
+		{InverseStrided(wgs84, make([]float64, 3), 2), "InverseStrided", "stride", ErrInvalidParam},
+	} {
+		var pe *ProjectionError
+		if !errors.As(test.err, &pe) || pe.Op != test.fn || pe.Param != test.param {
+			t.Errorf("expected %s's %s to be wrong, got %v", test.fn, test.param, test.err)
+			continue
+		}
+		if !errors.Is(test.err, test.cause) || !errors.Is(test.err, ErrCodeAPIMisuse) {
+			t.Errorf("%v doesn't match %v as API misuse", test.err, test.cause)
+		}
+	}
+}
+
+func TestNoConvergence(t *testing.T) {
+	_, err := phi2(0.5, math.NaN())
+	var pe *ProjectionError
+	if !errors.As(err, &pe) || pe.Op != "phi2" || pe.Code != ErrCodeNoConvergence {
+		t.Fatalf("expected phi2 not to converge, got %v", err)
+	}
+	if !errors.Is(err, ErrNoConvergence) || !errors.Is(err, ErrCodeCoordTransfm) {
+		t.Errorf("%v doesn't match its code", err)
+	}
+	if got, want := err.Error(), "phi2: The calculation did not converge"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestProjectionErrorText(t *testing.T) {
+	for _, test := range []struct {
+		err  *ProjectionError
+		text string
+	}{
+		{&ProjectionError{Code: ErrCodeIllegalArgValue, Proj: "utm", Param: "zone", Err: ErrInvalidParam},
+			"utm: +zone: We encountered an illegal parameter"},
+		{&ProjectionError{Code: ErrCodeOutsideProjectionDomain, Proj: "ortho", X: 1, Y: -0.5, Err: ErrPointNotVisible},
+			"ortho: (1, -0.5): The coordinate is not visible from the projection's centre"},
+		{&ProjectionError{Code: ErrCodeMissingArg, Param: "proj"},
+			"+proj: Missing argument"},
+		{&ProjectionError{Code: ErrCodeAPIMisuse, Op: "Transform", Param: "z", Err: ErrGeocentric},
+			"Transform: z: " + ErrGeocentric.Error()},
+		{&ProjectionError{Code: 1},
+			"Unknown error (code 1)"},
+	} {
+		if got := test.err.Error(); got != test.text {
+			t.Errorf("expected %q, got %q", test.text, got)
+		}
+	}
+}
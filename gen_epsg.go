@@ -0,0 +1,211 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+
+// gen_epsg writes epsg_table.go, the generated part of the EPSG registry,
+// from PROJ's proj.db.  It needs PROJ 7 or later installed, for the sqlite3
+// and projinfo tools:
+//
+//	go generate
+//	go run gen_epsg.go -db /usr/share/proj/proj.db
+//
+// Every geographic 2D, geocentric and projected CRS of the EPSG authority is
+// turned into a proj4 string by projinfo and kept if NewProjection accepts
+// it.  The codes dropped are summarised on stderr, with why, so the scope of
+// the registry never narrows without anyone noticing.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/samlecuyer/projectron"
+)
+
+var (
+	db  = flag.String("db", "", "path to proj.db; found with projinfo --searchpaths if empty")
+	out = flag.String("o", "epsg_table.go", "file to write")
+)
+
+// crsQuery lists the CRSs with the description of their first area of use.
+const crsQuery = `
+SELECT c.code, c.name, c.deprecated, COALESCE((
+	SELECT e.description FROM usage u
+	JOIN extent e ON e.auth_name = u.extent_auth_name AND e.code = u.extent_code
+	WHERE u.object_table_name = '%[1]s' AND u.object_auth_name = c.auth_name AND u.object_code = c.code
+	ORDER BY u.code LIMIT 1), '') AS area
+FROM %[1]s c
+WHERE c.auth_name = 'EPSG' %[2]s`
+
+type crs struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Deprecated int    `json:"deprecated"`
+	Area       string `json:"area"`
+
+	code int
+	defn string
+	skip string
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gen_epsg: ")
+	flag.Parse()
+	if *db == "" {
+		*db = findDB()
+	}
+
+	var all []*crs
+	for _, q := range []string{
+		fmt.Sprintf(crsQuery, "geodetic_crs", "AND c.type IN ('geographic 2D', 'geocentric')"),
+		fmt.Sprintf(crsQuery, "projected_crs", ""),
+	} {
+		cmd := exec.Command("sqlite3", "-json", *db, q)
+		cmd.Stderr = os.Stderr
+		b, err := cmd.Output()
+		if err != nil {
+			log.Fatalf("querying %s: %v", *db, err)
+		}
+		var rows []*crs
+		if len(bytes.TrimSpace(b)) > 0 {
+			if err := json.Unmarshal(b, &rows); err != nil {
+				log.Fatalf("reading the query results: %v", err)
+			}
+		}
+		all = append(all, rows...)
+	}
+
+	// projinfo reads proj.db afresh for every code, so run a few at once
+	work := make(chan *crs)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				define(c)
+			}
+		}()
+	}
+	for _, c := range all {
+		work <- c
+	}
+	close(work)
+	wg.Wait()
+
+	var kept []*crs
+	skipped := make(map[string][]int)
+	for _, c := range all {
+		if c.skip != "" {
+			skipped[c.skip] = append(skipped[c.skip], c.code)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].code < kept[j].code })
+	if err := write(kept); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("kept %d of %d CRSs", len(kept), len(all))
+	var reasons []string
+	for r := range skipped {
+		reasons = append(reasons, r)
+	}
+	sort.Slice(reasons, func(i, j int) bool { return len(skipped[reasons[i]]) > len(skipped[reasons[j]]) })
+	for _, r := range reasons {
+		codes := skipped[r]
+		sort.Ints(codes)
+		if len(codes) > 10 {
+			log.Printf("skipped %d: %s (EPSG:%d, ...)", len(codes), r, codes[0])
+		} else {
+			log.Printf("skipped %d: %s %v", len(codes), r, codes)
+		}
+	}
+}
+
+// findDB asks projinfo where PROJ keeps its data.
+func findDB() string {
+	var dirs []string
+	for _, env := range []string{"PROJ_DATA", "PROJ_LIB"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, filepath.SplitList(dir)...)
+		}
+	}
+	if b, err := exec.Command("projinfo", "--searchpaths").Output(); err == nil {
+		dirs = append(dirs, strings.Fields(string(b))...)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, "proj.db")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	log.Fatal("can't find proj.db; give it with -db")
+	return ""
+}
+
+// define fills in the proj4 string for c, or why it can't be carried.
+func define(c *crs) {
+	var err error
+	if c.code, err = strconv.Atoi(c.Code); err != nil {
+		c.skip = "code isn't a number"
+		return
+	}
+	b, err := exec.Command("projinfo", "-o", "PROJ", "-q", "EPSG:"+c.Code).Output()
+	if err != nil {
+		c.skip = "projinfo can't give a proj4 string"
+		return
+	}
+	var params []string
+	for _, p := range strings.Fields(string(b)) {
+		if p != "+no_defs" && p != "+type=crs" {
+			params = append(params, p)
+		}
+	}
+	c.defn = strings.Join(params, " ")
+	if _, err := projectron.NewProjection(c.defn); err != nil {
+		c.skip = err.Error()
+	}
+}
+
+func write(rows []*crs) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen_epsg.go from %s; DO NOT EDIT.\n\n", filepath.Base(*db))
+	buf.WriteString("package projectron\n\nfunc init() {\n")
+
+	areas := make(map[string]int)
+	buf.WriteString("epsgAreas = []string{\n")
+	for _, c := range rows {
+		if _, ok := areas[c.Area]; !ok {
+			areas[c.Area] = len(areas)
+			fmt.Fprintf(&buf, "%q,\n", c.Area)
+		}
+	}
+	buf.WriteString("}\nepsgTable = []epsgRow{\n")
+	for _, c := range rows {
+		fmt.Fprintf(&buf, "{%d, %q, %d, %t, %q},\n", c.code, c.Name, areas[c.Area], c.Deprecated != 0, c.defn)
+	}
+	buf.WriteString("}\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, src, 0644)
+}
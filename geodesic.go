@@ -0,0 +1,612 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"math"
+)
+
+// The geodesic problems on an ellipsoid with a unit semi-major axis,
+// solved with the series of C. F. F. Karney, "Algorithms for geodesics",
+// J. Geodesy 87, 43-55 (2013), as GeographicLib and PROJ do.  Unlike
+// Vincenty's formulae these converge everywhere, including for nearly
+// antipodal points, and are good to a few nanometres on the Earth.
+
+const (
+	geodOrder   = 6
+	geodMaxit1  = 20
+	geodMaxit2  = geodMaxit1 + 53 + 10
+	geodTiny    = 0x1p-511 // sqrt of the smallest normal float64
+	geodTol0    = 0x1p-52
+	geodTol1    = 200 * geodTol0
+	geodTol2    = 0x1p-26 // sqrt(geodTol0)
+	geodTolb    = geodTol0
+	geodXthresh = 1000 * geodTol2
+)
+
+// geodesic holds what depends only on the flattening.
+type geodesic struct {
+	f, f1, ep2, n float64
+	etol2         float64
+	a3x           [geodOrder]float64
+	c3x           [15]float64
+}
+
+func newGeodesic(f float64) *geodesic {
+	g := &geodesic{f: f, f1: 1 - f, n: f / (2 - f)}
+	g.ep2 = f * (2 - f) / (g.f1 * g.f1)
+	g.etol2 = 0.1 * geodTol2 / math.Sqrt(math.Max(0.001, math.Abs(f))*math.Min(1, 1-f/2)/2)
+
+	// the A3 and C3 coefficients as polynomials in n, highest power of eps first
+	a3 := []float64{
+		-3, 128,
+		-2, -3, 64,
+		-1, -3, -1, 16,
+		3, -1, -2, 8,
+		1, -1, 2,
+		1, 1,
+	}
+	o := 0
+	for j := geodOrder - 1; j >= 0; j-- {
+		m := geodOrder - j - 1
+		if j < m {
+			m = j
+		}
+		g.a3x[geodOrder-1-j] = polyval(a3[o:o+m+1], g.n) / a3[o+m+1]
+		o += m + 2
+	}
+	c3 := []float64{
+		3, 128,
+		2, 5, 128,
+		-1, 3, 3, 64,
+		-1, 0, 1, 8,
+		-1, 1, 4,
+		5, 256,
+		1, 3, 128,
+		-3, -2, 3, 64,
+		1, -3, 2, 32,
+		7, 512,
+		-10, 9, 384,
+		5, -9, 5, 192,
+		7, 512,
+		-14, 7, 512,
+		21, 2560,
+	}
+	o, k := 0, 0
+	for l := 1; l < geodOrder; l++ {
+		for j := geodOrder - 1; j >= l; j-- {
+			m := geodOrder - j - 1
+			if j < m {
+				m = j
+			}
+			g.c3x[k] = polyval(c3[o:o+m+1], g.n) / c3[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+	return g
+}
+
+// polyval evaluates the polynomial with coefficients p, highest power first.
+func polyval(p []float64, x float64) float64 {
+	y := 0.0
+	for _, c := range p {
+		y = y*x + c
+	}
+	return y
+}
+
+func norm2(s, c float64) (float64, float64) {
+	r := math.Hypot(s, c)
+	return s / r, c / r
+}
+
+// angRound drops the bits of tiny angles so that values very close to
+// zero are treated as zero.
+func angRound(x float64) float64 {
+	const z = 1.0 / 16
+	y := math.Abs(x)
+	if w := z - y; w > 0 {
+		y = z - w
+	}
+	return math.Copysign(y, x)
+}
+
+func a1m1f(eps float64) float64 {
+	t := polyval([]float64{1, 4, 64, 0}, eps*eps) / 256
+	return (t + eps) / (1 - eps)
+}
+
+func a2m1f(eps float64) float64 {
+	t := polyval([]float64{-11, -28, -192, 0}, eps*eps) / 256
+	return (t - eps) / (1 + eps)
+}
+
+var (
+	geodC1 = []float64{
+		-1, 6, -16, 32,
+		-9, 64, -128, 2048,
+		9, -16, 768,
+		3, -5, 512,
+		-7, 1280,
+		-7, 2048,
+	}
+	geodC1p = []float64{
+		205, -432, 768, 1536,
+		4005, -4736, 3840, 12288,
+		-225, 116, 384,
+		-7173, 2695, 7680,
+		3467, 7680,
+		38081, 61440,
+	}
+	geodC2 = []float64{
+		1, 2, 16, 32,
+		35, 64, 384, 2048,
+		15, 80, 768,
+		7, 35, 512,
+		63, 1280,
+		77, 2048,
+	}
+)
+
+// seriesCoeffs fills c[1:] from one of the C1, C1p and C2 tables, whose
+// terms are polynomials in eps².
+func seriesCoeffs(coeff []float64, eps float64, c []float64) {
+	eps2, d, o := eps*eps, eps, 0
+	for l := 1; l <= geodOrder; l++ {
+		m := (geodOrder - l) / 2
+		c[l] = d * polyval(coeff[o:o+m+1], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func (g *geodesic) a3f(eps float64) float64 {
+	return polyval(g.a3x[:], eps)
+}
+
+func (g *geodesic) c3f(eps float64, c []float64) {
+	mult, o := 1.0, 0
+	for l := 1; l < geodOrder; l++ {
+		m := geodOrder - l - 1
+		mult *= eps
+		c[l] = mult * polyval(g.c3x[o:o+m+1], eps)
+		o += m + 1
+	}
+}
+
+// sinSeries sums c[k]·sin(2kx) for k = 1..n with Clenshaw's method.
+func sinSeries(sinx, cosx float64, c []float64, n int) float64 {
+	ar := 2 * (cosx - sinx) * (cosx + sinx)
+	k := n + 1
+	var y0, y1 float64
+	if n&1 != 0 {
+		k--
+		y0 = c[k]
+	}
+	for n /= 2; n > 0; n-- {
+		k--
+		y1 = ar*y0 - y1 + c[k]
+		k--
+		y0 = ar*y1 - y0 + c[k]
+	}
+	return 2 * sinx * cosx * y0
+}
+
+// lengths returns the distance and the reduced length of the geodesic
+// segment sig12, both divided by b, and the secular term m0.
+func (g *geodesic) lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2 float64) (s12b, m12b, m0 float64) {
+	var ca, cb [geodOrder + 1]float64
+	a1 := a1m1f(eps)
+	seriesCoeffs(geodC1, eps, ca[:])
+	a2 := a2m1f(eps)
+	seriesCoeffs(geodC2, eps, cb[:])
+	m0 = a1 - a2
+	a1, a2 = 1+a1, 1+a2
+	b1 := sinSeries(ssig2, csig2, ca[:], geodOrder) - sinSeries(ssig1, csig1, ca[:], geodOrder)
+	b2 := sinSeries(ssig2, csig2, cb[:], geodOrder) - sinSeries(ssig1, csig1, cb[:], geodOrder)
+	s12b = a1 * (sig12 + b1)
+	j12 := m0*sig12 + (a1*b1 - a2*b2)
+	m12b = dn2*(csig1*ssig2) - dn1*(ssig1*csig2) - csig1*csig2*j12
+	return s12b, m12b, m0
+}
+
+// astroid solves k⁴+2k³-(x²+y²-1)k²-2y²k-y² = 0 for its positive root.
+func astroid(x, y float64) float64 {
+	p, q := x*x, y*y
+	r := (p + q - 1) / 6
+	if q == 0 && r <= 0 {
+		return 0
+	}
+	s := p * q / 4
+	r2 := r * r
+	r3 := r * r2
+	disc := s * (s + 2*r3)
+	u := r
+	if disc >= 0 {
+		t3 := s + r3
+		if t3 < 0 {
+			t3 -= math.Sqrt(disc)
+		} else {
+			t3 += math.Sqrt(disc)
+		}
+		t := math.Cbrt(t3)
+		u += t
+		if t != 0 {
+			u += r2 / t
+		}
+	} else {
+		ang := math.Atan2(math.Sqrt(-disc), -(s + r3))
+		u += 2 * r * math.Cos(ang/3)
+	}
+	v := math.Sqrt(u*u + q)
+	uv := u + v
+	if u < 0 {
+		uv = q / (v - u)
+	}
+	w := (uv - q) / (2 * v)
+	return uv / (math.Sqrt(uv+w*w) + w)
+}
+
+// inverseStart returns a starting azimuth for Newton's method or, for
+// short lines, the solution itself with sig12 >= 0.
+func (g *geodesic) inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12 float64) (sig12, salp1, calp1, salp2, calp2, dnm float64) {
+	sig12 = -1
+	sbet12 := sbet2*cbet1 - cbet2*sbet1
+	cbet12 := cbet2*cbet1 + sbet2*sbet1
+	sbet12a := sbet2*cbet1 + cbet2*sbet1
+	shortline := cbet12 >= 0 && sbet12 < 0.5 && cbet2*lam12 < 0.5
+	somg12, comg12 := slam12, clam12
+	if shortline {
+		sbetm2 := (sbet1 + sbet2) * (sbet1 + sbet2)
+		sbetm2 /= sbetm2 + (cbet1+cbet2)*(cbet1+cbet2)
+		dnm = math.Sqrt(1 + g.ep2*sbetm2)
+		somg12, comg12 = math.Sincos(lam12 / (g.f1 * dnm))
+	}
+
+	salp1 = cbet2 * somg12
+	if comg12 >= 0 {
+		calp1 = sbet12 + cbet2*sbet1*somg12*somg12/(1+comg12)
+	} else {
+		calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+	}
+	ssig12 := math.Hypot(salp1, calp1)
+	csig12 := sbet1*sbet2 + cbet1*cbet2*comg12
+
+	if shortline && ssig12 < g.etol2 {
+		// really short lines
+		salp2 = cbet1 * somg12
+		if comg12 >= 0 {
+			calp2 = sbet12 - cbet1*sbet2*somg12*somg12/(1+comg12)
+		} else {
+			calp2 = sbet12 - cbet1*sbet2*(1-comg12)
+		}
+		salp2, calp2 = norm2(salp2, calp2)
+		sig12 = math.Atan2(ssig12, csig12)
+	} else if math.Abs(g.n) > 0.1 || csig12 >= 0 || ssig12 >= 6*math.Abs(g.n)*math.Pi*cbet1*cbet1 {
+		// the zeroth order spherical approximation is good enough
+	} else {
+		// scale to coordinates where the antipode is at the origin and
+		// the singular point at (-1, 0)
+		var x, y, lamscale, betscale float64
+		lam12x := math.Atan2(-slam12, -clam12)
+		if g.f >= 0 {
+			k2 := sbet1 * sbet1 * g.ep2
+			eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+			lamscale = g.f * cbet1 * g.a3f(eps) * math.Pi
+			betscale = lamscale * cbet1
+			x, y = lam12x/lamscale, sbet12a/betscale
+		} else {
+			cbet12a := cbet2*cbet1 - sbet2*sbet1
+			bet12a := math.Atan2(sbet12a, cbet12a)
+			_, m12b, m0 := g.lengths(g.n, math.Pi+bet12a, sbet1, -cbet1, dn1, sbet2, cbet2, dn2)
+			x = -1 + m12b/(cbet1*cbet2*m0*math.Pi)
+			if x < -0.01 {
+				betscale = sbet12a / x
+			} else {
+				betscale = -g.f * cbet1 * cbet1 * math.Pi
+			}
+			lamscale = betscale / cbet1
+			y = lam12x / lamscale
+		}
+
+		if y > -geodTol1 && x > -1-geodXthresh {
+			// strip near the cut
+			if g.f >= 0 {
+				salp1 = math.Min(1, -x)
+				calp1 = -math.Sqrt(1 - salp1*salp1)
+			} else {
+				calp1 = x
+				if x > -geodTol1 {
+					calp1 = math.Max(0, x)
+				} else {
+					calp1 = math.Max(-1, x)
+				}
+				salp1 = math.Sqrt(1 - calp1*calp1)
+			}
+		} else {
+			k := astroid(x, y)
+			var omg12a float64
+			if g.f >= 0 {
+				omg12a = lamscale * (-x * k / (1 + k))
+			} else {
+				omg12a = lamscale * (-y * (1 + k) / k)
+			}
+			somg12, comg12 = math.Sincos(omg12a)
+			comg12 = -comg12
+			salp1 = cbet2 * somg12
+			calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+		}
+	}
+	// the backwards test lets NaN through
+	if !(salp1 <= 0) {
+		salp1, calp1 = norm2(salp1, calp1)
+	} else {
+		salp1, calp1 = 1, 0
+	}
+	return sig12, salp1, calp1, salp2, calp2, dnm
+}
+
+// lambda12Result is the geodesic that lambda12 found from a trial azimuth.
+type lambda12Result struct {
+	lam12, dlam12                   float64
+	salp2, calp2, sig12             float64
+	ssig1, csig1, ssig2, csig2, eps float64
+}
+
+// lambda12 returns the longitude difference reached, and its derivative,
+// when leaving the reduced latitude bet1 on the azimuth alp1 and stopping
+// at bet2, measured from lam120.
+func (g *geodesic) lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam120, clam120 float64, diffp bool) (r lambda12Result) {
+	if sbet1 == 0 && calp1 == 0 {
+		// break the degeneracy of the equatorial line
+		calp1 = -geodTiny
+	}
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	somg1 := salp0 * sbet1
+	comg1 := calp1 * cbet1
+	r.ssig1, r.csig1 = norm2(sbet1, comg1)
+
+	// enforce the symmetries when |bet2| = -bet1
+	r.salp2 = salp1
+	if cbet2 != cbet1 {
+		r.salp2 = salp0 / cbet2
+	}
+	if cbet2 != cbet1 || math.Abs(sbet2) != -sbet1 {
+		t := (sbet1 - sbet2) * (sbet1 + sbet2)
+		if cbet1 < -sbet1 {
+			t = (cbet2 - cbet1) * (cbet1 + cbet2)
+		}
+		r.calp2 = math.Sqrt(calp1*cbet1*calp1*cbet1+t) / cbet2
+	} else {
+		r.calp2 = math.Abs(calp1)
+	}
+	somg2 := salp0 * sbet2
+	comg2 := r.calp2 * cbet2
+	r.ssig2, r.csig2 = norm2(sbet2, comg2)
+
+	r.sig12 = math.Atan2(math.Max(0, r.csig1*r.ssig2-r.ssig1*r.csig2), r.csig1*r.csig2+r.ssig1*r.ssig2)
+	somg12 := math.Max(0, comg1*somg2-somg1*comg2)
+	comg12 := comg1*comg2 + somg1*somg2
+	eta := math.Atan2(somg12*clam120-comg12*slam120, comg12*clam120+somg12*slam120)
+
+	k2 := calp0 * calp0 * g.ep2
+	r.eps = k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+	var ca [geodOrder]float64
+	g.c3f(r.eps, ca[:])
+	b312 := sinSeries(r.ssig2, r.csig2, ca[:], geodOrder-1) - sinSeries(r.ssig1, r.csig1, ca[:], geodOrder-1)
+	r.lam12 = eta - g.f*g.a3f(r.eps)*salp0*(r.sig12+b312)
+
+	if diffp {
+		if r.calp2 == 0 {
+			r.dlam12 = -2 * g.f1 * dn1 / sbet1
+		} else {
+			_, m12b, _ := g.lengths(r.eps, r.sig12, r.ssig1, r.csig1, dn1, r.ssig2, r.csig2, dn2)
+			r.dlam12 = m12b * g.f1 / (r.calp2 * cbet2)
+		}
+	}
+	return r
+}
+
+// inverse returns the distance and the forward azimuths at both ends of
+// the geodesic between lat1/lng1 and lat2/lng2.  Everything is in radians.
+func (g *geodesic) inverse(lat1, lng1, lat2, lng2 float64) (s12, azi1, azi2 float64) {
+	// bring the points into the canonical form
+	//     0 <= lon12 <= pi, -pi/2 <= lat1 <= -0, lat1 <= lat2 <= -lat1
+	// and remember how to undo it
+	lon12 := math.Remainder(lng2-lng1, 2*math.Pi)
+	lonsign := 1.0
+	if math.Signbit(lon12) {
+		lonsign = -1
+	}
+	lon12 *= lonsign
+	lon12s := math.Pi - lon12
+	slam12, clam12 := math.Sincos(lon12)
+	if lon12 > math.Pi/2 {
+		// from the supplement so that lon12 = pi gives exactly 0 and -1
+		slam12, clam12 = math.Sincos(lon12s)
+		clam12 = -clam12
+	}
+
+	lat1, lat2 = angRound(lat1), angRound(lat2)
+	swapp := 1.0
+	if math.Abs(lat1) < math.Abs(lat2) || math.IsNaN(lat2) {
+		swapp = -1
+		lonsign = -lonsign
+		lat1, lat2 = lat2, lat1
+	}
+	latsign := -1.0
+	if math.Signbit(lat1) {
+		latsign = 1
+	}
+	lat1 *= latsign
+	lat2 *= latsign
+
+	sbet1, cbet1 := math.Sincos(lat1)
+	sbet1, cbet1 = norm2(sbet1*g.f1, cbet1)
+	cbet1 = math.Max(geodTiny, cbet1)
+	sbet2, cbet2 := math.Sincos(lat2)
+	sbet2, cbet2 = norm2(sbet2*g.f1, cbet2)
+	cbet2 = math.Max(geodTiny, cbet2)
+
+	// force bet2 = ±bet1 exactly when they're that close
+	if cbet1 < -sbet1 {
+		if cbet2 == cbet1 {
+			sbet2 = math.Copysign(sbet1, sbet2)
+		}
+	} else if math.Abs(sbet2) == -sbet1 {
+		cbet2 = cbet1
+	}
+	dn1 := math.Sqrt(1 + g.ep2*sbet1*sbet1)
+	dn2 := math.Sqrt(1 + g.ep2*sbet2*sbet2)
+
+	var sig12, salp1, calp1, salp2, calp2 float64
+	b := g.f1
+	meridian := lat1 == -math.Pi/2 || slam12 == 0
+	if meridian {
+		// the points are on one meridian, so the geodesic might follow it
+		salp1, calp1 = slam12, clam12
+		salp2, calp2 = 0, 1
+		ssig1, csig1 := sbet1, calp1*cbet1
+		ssig2, csig2 := sbet2, calp2*cbet2
+		sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+		s12x, m12x, _ := g.lengths(g.n, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2)
+		if sig12 < 1 || m12x >= 0 {
+			if sig12 < 3*geodTiny || (sig12 < geodTol0 && (s12x < 0 || m12x < 0)) {
+				s12x = 0
+			}
+			s12 = s12x * b
+		} else {
+			// prolate and too close to the antipode
+			meridian = false
+		}
+	}
+
+	if !meridian && sbet1 == 0 && (g.f <= 0 || lon12s >= g.f*math.Pi) {
+		// along the equator
+		salp1, calp1, salp2, calp2 = 1, 0, 1, 0
+		s12 = lon12
+	} else if !meridian {
+		var dnm float64
+		sig12, salp1, calp1, salp2, calp2, dnm = g.inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lon12, slam12, clam12)
+		if sig12 >= 0 {
+			// short lines
+			s12 = sig12 * b * dnm
+		} else {
+			// Newton's method on lambda12(alp1) = lon12, keeping a bracket
+			// on the root to fall back on with bisection
+			var r lambda12Result
+			salp1a, calp1a, salp1b, calp1b := geodTiny, 1.0, geodTiny, -1.0
+			tripn, tripb := false, false
+			for numit := 0; ; numit++ {
+				r = g.lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam12, clam12, numit < geodMaxit1)
+				v := r.lam12
+				tol := geodTol0
+				if tripn {
+					tol *= 8
+				}
+				if tripb || !(math.Abs(v) >= tol) || numit == geodMaxit2 {
+					break
+				}
+				if v > 0 && (numit > geodMaxit1 || calp1/salp1 > calp1b/salp1b) {
+					salp1b, calp1b = salp1, calp1
+				} else if v < 0 && (numit > geodMaxit1 || calp1/salp1 < calp1a/salp1a) {
+					salp1a, calp1a = salp1, calp1
+				}
+				if numit < geodMaxit1 && r.dlam12 > 0 {
+					if dalp1 := -v / r.dlam12; math.Abs(dalp1) < math.Pi {
+						sdalp1, cdalp1 := math.Sincos(dalp1)
+						if nsalp1 := salp1*cdalp1 + calp1*sdalp1; nsalp1 > 0 {
+							calp1 = calp1*cdalp1 - salp1*sdalp1
+							salp1, calp1 = norm2(nsalp1, calp1)
+							tripn = math.Abs(v) <= 16*geodTol0
+							continue
+						}
+					}
+				}
+				salp1, calp1 = norm2((salp1a+salp1b)/2, (calp1a+calp1b)/2)
+				tripn = false
+				tripb = math.Abs(salp1a-salp1)+(calp1a-calp1) < geodTolb ||
+					math.Abs(salp1-salp1b)+(calp1-calp1b) < geodTolb
+			}
+			salp2, calp2 = r.salp2, r.calp2
+			s12x, _, _ := g.lengths(r.eps, r.sig12, r.ssig1, r.csig1, dn1, r.ssig2, r.csig2, dn2)
+			s12 = s12x * b
+		}
+	}
+
+	if swapp < 0 {
+		salp1, salp2 = salp2, salp1
+		calp1, calp2 = calp2, calp1
+	}
+	salp1 *= swapp * lonsign
+	calp1 *= swapp * latsign
+	salp2 *= swapp * lonsign
+	calp2 *= swapp * latsign
+	return 0 + s12, math.Atan2(salp1, calp1), math.Atan2(salp2, calp2)
+}
+
+// direct returns the end point and its forward azimuth after travelling
+// s12 from lat1/lng1 on the initial azimuth azi1.
+func (g *geodesic) direct(lat1, lng1, azi1, s12 float64) (lat2, lng2, azi2 float64) {
+	b := g.f1
+	salp1, calp1 := math.Sincos(azi1)
+	sbet1, cbet1 := math.Sincos(angRound(lat1))
+	sbet1, cbet1 = norm2(sbet1*g.f1, cbet1)
+	cbet1 = math.Max(geodTiny, cbet1)
+
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+	ssig1, somg1 := sbet1, salp0*sbet1
+	csig1 := 1.0
+	if sbet1 != 0 || calp1 != 0 {
+		csig1 = cbet1 * calp1
+	}
+	comg1 := csig1
+	ssig1, csig1 = norm2(ssig1, csig1)
+
+	k2 := calp0 * calp0 * g.ep2
+	eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+	var c1a, c1pa, c3a [geodOrder + 1]float64
+	a1 := 1 + a1m1f(eps)
+	seriesCoeffs(geodC1, eps, c1a[:])
+	seriesCoeffs(geodC1p, eps, c1pa[:])
+	g.c3f(eps, c3a[:])
+	b11 := sinSeries(ssig1, csig1, c1a[:], geodOrder)
+	s, c := math.Sincos(b11)
+	stau1, ctau1 := ssig1*c+csig1*s, csig1*c-ssig1*s
+
+	tau12 := s12 / (b * a1)
+	s, c = math.Sincos(tau12)
+	b12 := -sinSeries(stau1*c+ctau1*s, ctau1*c-stau1*s, c1pa[:], geodOrder)
+	sig12 := tau12 - (b12 - b11)
+	ssig12, csig12 := math.Sincos(sig12)
+	if math.Abs(g.f) > 0.01 {
+		// the reverted series isn't accurate enough, so take a Newton step
+		ssig2 := ssig1*csig12 + csig1*ssig12
+		csig2 := csig1*csig12 - ssig1*ssig12
+		b12 = sinSeries(ssig2, csig2, c1a[:], geodOrder)
+		serr := a1*(sig12+(b12-b11)) - s12/b
+		sig12 -= serr / math.Sqrt(1+k2*ssig2*ssig2)
+		ssig12, csig12 = math.Sincos(sig12)
+	}
+
+	ssig2 := ssig1*csig12 + csig1*ssig12
+	csig2 := csig1*csig12 - ssig1*ssig12
+	sbet2 := calp0 * ssig2
+	cbet2 := math.Hypot(salp0, calp0*csig2)
+	if cbet2 == 0 {
+		cbet2, csig2 = geodTiny, geodTiny
+	}
+	salp2, calp2 := salp0, calp0*csig2
+
+	somg2, comg2 := salp0*ssig2, csig2
+	omg12 := math.Atan2(somg2*comg1-comg2*somg1, comg2*comg1+somg2*somg1)
+	lam12 := omg12 - g.f*salp0*g.a3f(eps)*(sig12+(sinSeries(ssig2, csig2, c3a[:], geodOrder-1)-
+		sinSeries(ssig1, csig1, c3a[:], geodOrder-1)))
+	return math.Atan2(sbet2, g.f1*cbet2), lng1 + lam12, math.Atan2(salp2, calp2)
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeodesic(t *testing.T) {
+	const r2d = 1 / d2r
+	const a = 6378137
+	g := newGeodesic(1 / 298.257223563)
+
+	// Karney (2013), the examples in sections 3 and 4; the inverse one is
+	// nearly antipodal
+	s12, azi1, azi2 := g.inverse(-30*d2r, 0, 29.9*d2r, 179.8*d2r)
+	if !within(s12*a, 19989832.827610, 1.0e-6) || !within(azi1*r2d, 161.890524736, 1.0e-9) || !within(azi2*r2d, 18.090737246, 1.0e-9) {
+		t.Errorf("inverse: %.6f %.9f %.9f", s12*a, azi1*r2d, azi2*r2d)
+	}
+	lat2, lng2, azi2 := g.direct(40*d2r, 0, 30*d2r, 10000000.0/a)
+	if !within(lat2*r2d, 41.79331020506, 1.0e-11) || !within(lng2*r2d, 137.84490004377, 1.0e-11) || !within(azi2*r2d, 149.09016931807, 1.0e-11) {
+		t.Errorf("direct: %.11f %.11f %.11f", lat2*r2d, lng2*r2d, azi2*r2d)
+	}
+
+	// GeographicLib's GeodSolve documentation: JFK to Singapore
+	s12, azi1, azi2 = g.inverse((40+38.0/60+23.0/3600)*d2r, -(73+46.0/60+44.0/3600)*d2r,
+		(1+21.0/60+33.0/3600)*d2r, (103+59.0/60+22.0/3600)*d2r)
+	if !within(s12*a, 15347628, 0.5) || !within(azi1*r2d, 3+18.0/60+29.9/3600, 0.05/3600) || !within(azi2*r2d, 177+29.0/60+9.2/3600, 0.05/3600) {
+		t.Errorf("inverse: %.3f %.9f %.9f", s12*a, azi1*r2d, azi2*r2d)
+	}
+
+	// the direct problem undoes the inverse everywhere, antipodes included
+	for _, tt := range [][4]float64{
+		{40, -100, 80, -40}, {40, -100, -40, 80}, {40, -100, -39.9, 79.7},
+		{0, 0, 0, 179.5}, {0, 0, 0.5, 179.5}, {-30, 0, 30, 180},
+		{10, 20, 10.000001, 20}, {0, 0, 0, 0}, {89, 0, -89.5, 0},
+	} {
+		lat1, lng1, lat2, lng2 := tt[0]*d2r, tt[1]*d2r, tt[2]*d2r, tt[3]*d2r
+		s12, azi1, _ := g.inverse(lat1, lng1, lat2, lng2)
+		lat, lng, _ := g.direct(lat1, lng1, azi1, s12)
+		if math.IsNaN(s12) || !within(lat, lat2, 1.0e-12) || !within(adjLng(lng-lng2), 0, 1.0e-12) {
+			t.Errorf("%v: %.12f %.12f after %f", tt, lat*r2d, lng*r2d, s12*a)
+		}
+	}
+}
@@ -0,0 +1,608 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type lp struct {
+	lam, phi float64
+}
+
+// subgrid is one rectangular table of shifts.  Longitudes increase to the
+// east and the shifts are stored in radians, with the longitude shift
+// positive to the west as in the NTv2 format.
+type subgrid struct {
+	name     string
+	ll, del  lp
+	cols     int
+	rows     int
+	cvs      []float32 // lam, phi pairs, row by row from the south west
+	children []*subgrid
+}
+
+// grid is a loaded grid shift file.
+type grid struct {
+	name     string
+	format   string
+	subgrids []*subgrid
+}
+
+// nullGrid covers the whole world with a shift of zero.
+var nullGrid = &grid{
+	name:   "null",
+	format: "null",
+	subgrids: []*subgrid{{
+		name: "null",
+		ll:   lp{-math.Pi, -half_pi},
+		del:  lp{math.Pi, half_pi},
+		cols: 3,
+		rows: 3,
+		cvs:  make([]float32, 18),
+	}},
+}
+
+// GridProvider finds grid files named in +nadgrids and +geoidgrids, so they
+// can come from somewhere other than the file system, like an embed.FS.
+type GridProvider interface {
+	// OpenGrid opens the named grid file, returning ErrGridNotFound, or an
+	// error that wraps it or fs.ErrNotExist, if there's no such grid.
+	OpenGrid(name string) (io.ReadCloser, error)
+}
+
+// DirGridProvider searches a list of directories for grid files with
+// relative names.  Absolute names are opened as they are.
+type DirGridProvider []string
+
+func (d DirGridProvider) OpenGrid(name string) (io.ReadCloser, error) {
+	if filepath.IsAbs(name) {
+		if f, err := os.Open(name); err == nil {
+			return f, nil
+		}
+		return nil, ErrGridNotFound
+	}
+	for _, dir := range d {
+		if f, err := os.Open(filepath.Join(dir, name)); err == nil {
+			return f, nil
+		}
+	}
+	return nil, ErrGridNotFound
+}
+
+// FSGridProvider reads grid files from an fs.FS.
+type FSGridProvider struct {
+	fs.FS
+}
+
+func (p FSGridProvider) OpenGrid(name string) (io.ReadCloser, error) {
+	f, err := p.Open(strings.TrimPrefix(path.Clean("/"+name), "/"))
+	if err != nil {
+		return nil, ErrGridNotFound
+	}
+	return f, nil
+}
+
+// projLibProvider is the default GridProvider, which searches each entry of
+// $PROJ_LIB as it is at the time, then the working directory.
+type projLibProvider struct{}
+
+func (projLibProvider) OpenGrid(name string) (io.ReadCloser, error) {
+	var dirs DirGridProvider
+	if lib := os.Getenv("PROJ_LIB"); lib != "" {
+		dirs = append(dirs, filepath.SplitList(lib)...)
+	}
+	return append(dirs, ".").OpenGrid(name)
+}
+
+// gridSource caches the grids loaded from a GridProvider.
+type gridSource struct {
+	provider GridProvider
+	mu       sync.Mutex
+	grids    map[string]*grid
+}
+
+var gridSources = struct {
+	sync.Mutex
+	current *gridSource
+}{current: &gridSource{provider: projLibProvider{}, grids: make(map[string]*grid)}}
+
+// SetGridProvider changes where grid files are found for projections created
+// from now on, and starts a new cache for them.  A nil provider restores the
+// default search of $PROJ_LIB and the working directory.
+func SetGridProvider(provider GridProvider) {
+	if provider == nil {
+		provider = projLibProvider{}
+	}
+	gridSources.Lock()
+	defer gridSources.Unlock()
+	gridSources.current = &gridSource{provider: provider, grids: make(map[string]*grid)}
+}
+
+func currentGridSource() *gridSource {
+	gridSources.Lock()
+	defer gridSources.Unlock()
+	return gridSources.current
+}
+
+// load reads and caches the named grid file.
+func (s *gridSource) load(name string) (*grid, error) {
+	if name == "null" {
+		return nullGrid, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.grids[name]; ok {
+		return g, nil
+	}
+	f, err := s.provider.OpenGrid(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	g, err := parseGrid(name, data)
+	if err != nil {
+		return nil, err
+	}
+	s.grids[name] = g
+	return g, nil
+}
+
+// parseGrid sniffs the format of a grid file and parses it.  Anything
+// unrecognised is assumed to be an original CTable file, which has no magic.
+func parseGrid(name string, data []byte) (*grid, error) {
+	switch {
+	case strings.HasSuffix(strings.ToLower(name), ".gtx"):
+		return parseGTX(name, data)
+	case bytes.HasPrefix(data, []byte("HEADER")):
+		return parseNTv1(name, data)
+	case bytes.HasPrefix(data, []byte("NUM_OREC")):
+		return parseNTv2(name, data)
+	case bytes.HasPrefix(data, []byte("CTABLE V2")):
+		return parseCTable2(name, data)
+	}
+	return parseCTable(name, data)
+}
+
+// loadList resolves a +nadgrids or +geoidgrids list.  Names prefixed
+// with @ are optional and skipped if they can't be found; any other missing
+// grid is an error, as is a horizontal grid where a vertical one is wanted
+// or vice versa.
+func (s *gridSource) loadList(list string, vertical bool) ([]*grid, error) {
+	var grids []*grid
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		optional := strings.HasPrefix(name, "@")
+		name = strings.TrimPrefix(name, "@")
+		if name == "" {
+			continue
+		}
+		g, err := s.load(name)
+		if optional && (errors.Is(err, ErrGridNotFound) || errors.Is(err, fs.ErrNotExist)) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if (g.format == "gtx") != vertical {
+			return nil, ErrGridFormat
+		}
+		grids = append(grids, g)
+	}
+	return grids, nil
+}
+
+// contains reports whether the point falls within the subgrid, allowing a
+// little slack at the edges.
+func (sg *subgrid) contains(in lp) bool {
+	eps := (math.Abs(sg.del.phi) + math.Abs(sg.del.lam)) / 10000
+	return !(sg.ll.phi-eps > in.phi || sg.ll.lam-eps > in.lam ||
+		sg.ll.phi+float64(sg.rows-1)*sg.del.phi+eps < in.phi ||
+		sg.ll.lam+float64(sg.cols-1)*sg.del.lam+eps < in.lam)
+}
+
+// find returns the finest subgrid covering the point.
+func (g *grid) find(in lp) *subgrid {
+	for _, sg := range g.subgrids {
+		if !sg.contains(in) {
+			continue
+		}
+		for found := true; found; {
+			found = false
+			for _, child := range sg.children {
+				if child.contains(in) {
+					sg = child
+					found = true
+					break
+				}
+			}
+		}
+		return sg
+	}
+	return nil
+}
+
+// cell finds the nodes around t, which is relative to the south west corner
+// of the subgrid, and their weights for bilinear interpolation.
+func (sg *subgrid) cell(t lp) (idx [4]int, w [4]float64, ok bool) {
+	t.lam /= sg.del.lam
+	t.phi /= sg.del.phi
+	ilam, iphi := math.Floor(t.lam), math.Floor(t.phi)
+	flam, fphi := t.lam-ilam, t.phi-iphi
+	col, row := int(ilam), int(iphi)
+
+	if col < 0 {
+		if col == -1 && flam > 0.99999999999 {
+			col++
+			flam = 0
+		} else {
+			return idx, w, false
+		}
+	} else if col+1 >= sg.cols {
+		if col+1 == sg.cols && flam < 1e-11 {
+			col--
+			flam = 1
+		} else {
+			return idx, w, false
+		}
+	}
+	if row < 0 {
+		if row == -1 && fphi > 0.99999999999 {
+			row++
+			fphi = 0
+		} else {
+			return idx, w, false
+		}
+	} else if row+1 >= sg.rows {
+		if row+1 == sg.rows && fphi < 1e-11 {
+			row--
+			fphi = 1
+		} else {
+			return idx, w, false
+		}
+	}
+
+	i00 := row*sg.cols + col
+	m11 := flam * fphi
+	m10 := flam - m11
+	m01 := fphi - m11
+	m00 := 1 - flam - m01
+	return [4]int{i00, i00 + 1, i00 + sg.cols, i00 + sg.cols + 1}, [4]float64{m00, m10, m01, m11}, true
+}
+
+// interpolate returns the bilinearly interpolated shift at t, which is
+// relative to the south west corner of the subgrid.
+func (sg *subgrid) interpolate(t lp) (lp, bool) {
+	idx, w, ok := sg.cell(t)
+	if !ok {
+		return lp{}, false
+	}
+	var out lp
+	for i, j := range idx {
+		out.lam += w[i] * float64(sg.cvs[2*j])
+		out.phi += w[i] * float64(sg.cvs[2*j+1])
+	}
+	return out, true
+}
+
+// convert applies the shift of the subgrid to in, or removes it when
+// inverse is set, which takes a few iterations.
+func (sg *subgrid) convert(in lp, inverse bool) (lp, error) {
+	tb := lp{in.lam - sg.ll.lam, in.phi - sg.ll.phi}
+	tb.lam = adjLng(tb.lam-math.Pi) + math.Pi
+	t, ok := sg.interpolate(tb)
+	if !ok {
+		return in, ErrOutsideGrid
+	}
+	if !inverse {
+		return lp{in.lam - t.lam, in.phi + t.phi}, nil
+	}
+
+	t.lam = tb.lam + t.lam
+	t.phi = tb.phi - t.phi
+	for i := 0; ; i++ {
+		if i == 10 {
+			return in, noConvergence("inverse grid shift")
+		}
+		// like PROJ, settle for the estimate so far if the iteration
+		// wanders off the edge of the grid
+		del, ok := sg.interpolate(t)
+		if !ok {
+			break
+		}
+		dif := lp{t.lam - del.lam - tb.lam, t.phi + del.phi - tb.phi}
+		t.lam -= dif.lam
+		t.phi -= dif.phi
+		if dif.lam*dif.lam+dif.phi*dif.phi <= 1e-24 {
+			break
+		}
+	}
+	return lp{adjLng(t.lam + sg.ll.lam), t.phi + sg.ll.phi}, nil
+}
+
+// applyGridshift shifts each point with the first grid in the list that
+// covers it.  Errors are the point's, with p as the projection.
+func (p *pj) applyGridshift(grids []*grid, inverse bool, x, y []float64) error {
+	for i := range x {
+		if x[i] == hugeVal {
+			continue
+		}
+		in := lp{x[i], y[i]}
+		var sg *subgrid
+		for _, g := range grids {
+			if sg = g.find(in); sg != nil {
+				break
+			}
+		}
+		if sg == nil {
+			return p.coordError(ErrOutsideGrid, x[i], y[i])
+		}
+		out, err := sg.convert(in, inverse)
+		if err != nil {
+			return p.coordError(err, x[i], y[i])
+		}
+		x[i], y[i] = out.lam, out.phi
+	}
+	return nil
+}
+
+// applyVGridshift adds the geoid height from the first grid that covers each
+// point to z, taking orthometric heights to ellipsoidal ones, or removes it
+// when inverse is set.  Errors are the point's, with p as the projection.
+func (p *pj) applyVGridshift(grids []*grid, inverse bool, x, y, z []float64) error {
+	for i := range x {
+		if x[i] == hugeVal {
+			continue
+		}
+		in := lp{x[i], y[i]}
+		value, found := 0., false
+		for _, g := range grids {
+			if sg := g.find(in); sg != nil {
+				if value, found = sg.height(in); found {
+					break
+				}
+			}
+		}
+		if !found {
+			return p.coordError(ErrOutsideGrid, x[i], y[i])
+		}
+		if inverse {
+			z[i] -= value
+		} else {
+			z[i] += value
+		}
+	}
+	return nil
+}
+
+// gtxNoData marks cells without a geoid height in GTX files.
+const gtxNoData = -88.8888
+
+// height interpolates a vertical subgrid, which holds one value per node.
+// Points next to missing values aren't covered.
+func (sg *subgrid) height(in lp) (float64, bool) {
+	t := lp{in.lam - sg.ll.lam, in.phi - sg.ll.phi}
+	t.lam = adjLng(t.lam-math.Pi) + math.Pi
+	idx, w, ok := sg.cell(t)
+	if !ok {
+		return 0, false
+	}
+	value := 0.
+	for i, j := range idx {
+		if math.Abs(float64(sg.cvs[j])-gtxNoData) < 1e-4 {
+			return 0, false
+		}
+		value += w[i] * float64(sg.cvs[j])
+	}
+	return value, true
+}
+
+// parseGTX reads a NOAA vertical datum (.gtx) file, a big endian grid of
+// heights in metres, in rows from the south each running west to east.
+func parseGTX(name string, data []byte) (*grid, error) {
+	const headerSize = 40
+	if len(data) < headerSize {
+		return nil, ErrGridFormat
+	}
+	order := binary.BigEndian
+	double := func(off int) float64 { return math.Float64frombits(order.Uint64(data[off:])) }
+	lat0, lng0 := double(0), double(8)
+	latInc, lngInc := double(16), double(24)
+	rows := int(int32(order.Uint32(data[32:])))
+	cols := int(int32(order.Uint32(data[36:])))
+	if rows < 1 || cols < 1 || latInc <= 0 || lngInc <= 0 || len(data) < headerSize+4*rows*cols {
+		return nil, ErrGridFormat
+	}
+	// some grids run from 0 to 360
+	if lng0 >= 180 {
+		lng0 -= 360
+	}
+
+	sg := &subgrid{name: name, cols: cols, rows: rows}
+	sg.ll = lp{lng0 * d2r, lat0 * d2r}
+	sg.del = lp{lngInc * d2r, latInc * d2r}
+	sg.cvs = make([]float32, rows*cols)
+	for i := range sg.cvs {
+		sg.cvs[i] = math.Float32frombits(order.Uint32(data[headerSize+4*i:]))
+	}
+	return &grid{name: name, format: "gtx", subgrids: []*subgrid{sg}}, nil
+}
+
+// parseNTv2 reads a Canadian NTv2 (.gsb) file, which holds a hierarchy of
+// subgrids in either byte order.
+func parseNTv2(name string, data []byte) (*grid, error) {
+	if len(data) < 11*16 {
+		return nil, ErrGridFormat
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[8:12]) != 11 {
+		order = binary.BigEndian
+		if order.Uint32(data[8:12]) != 11 {
+			return nil, ErrGridFormat
+		}
+	}
+	numFiles := int(order.Uint32(data[2*16+8:]))
+	r := bytes.NewReader(data[11*16:])
+
+	g := &grid{name: name, format: "ntv2"}
+	byName := make(map[string]*subgrid)
+	for f := 0; f < numFiles; f++ {
+		var hdr [11 * 16]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, ErrGridFormat
+		}
+		if string(hdr[:8]) != "SUB_NAME" {
+			return nil, ErrGridFormat
+		}
+		field := func(i int) []byte { return hdr[i*16+8 : i*16+16] }
+		double := func(i int) float64 { return math.Float64frombits(order.Uint64(field(i))) }
+
+		sg := &subgrid{name: strings.TrimSpace(string(field(0)))}
+		parent := strings.TrimSpace(string(field(1)))
+		// NTv2 longitudes are positive to the west
+		sLat, nLat := double(4), double(5)
+		eLng, wLng := double(6), double(7)
+		latInc, lngInc := double(8), double(9)
+		sg.ll = lp{-wLng * sec2rad, sLat * sec2rad}
+		sg.del = lp{lngInc * sec2rad, latInc * sec2rad}
+		sg.cols = gridNodes(eLng, wLng, lngInc)
+		sg.rows = gridNodes(sLat, nLat, latInc)
+		count := int(order.Uint32(field(10)))
+		if sg.cols < 1 || sg.rows < 1 || count != sg.cols*sg.rows || 16*count > r.Len() {
+			return nil, ErrGridFormat
+		}
+
+		// rows run from south to north, each from east to west
+		sg.cvs = make([]float32, 2*count)
+		var rec [16]byte
+		for row := 0; row < sg.rows; row++ {
+			for i := 0; i < sg.cols; i++ {
+				if _, err := io.ReadFull(r, rec[:]); err != nil {
+					return nil, ErrGridFormat
+				}
+				j := 2 * (row*sg.cols + sg.cols - i - 1)
+				sg.cvs[j] = float32(float64(math.Float32frombits(order.Uint32(rec[4:8]))) * sec2rad)
+				sg.cvs[j+1] = float32(float64(math.Float32frombits(order.Uint32(rec[0:4]))) * sec2rad)
+			}
+		}
+
+		if p, ok := byName[parent]; ok {
+			p.children = append(p.children, sg)
+		} else {
+			g.subgrids = append(g.subgrids, sg)
+		}
+		byName[sg.name] = sg
+	}
+	return g, nil
+}
+
+// parseNTv1 reads an original Canadian NTv1 file, which is always big
+// endian and holds a single grid of double precision shifts.
+func parseNTv1(name string, data []byte) (*grid, error) {
+	const headerSize = 11 * 16
+	if len(data) < headerSize {
+		return nil, ErrGridFormat
+	}
+	order := binary.BigEndian
+	if order.Uint32(data[8:12]) != 12 {
+		return nil, ErrGridFormat
+	}
+	double := func(off int) float64 { return math.Float64frombits(order.Uint64(data[off:])) }
+
+	// the limits are in degrees, with longitudes positive to the west,
+	// while the shifts are in seconds
+	sLat, nLat := double(24), double(40)
+	eLng, wLng := double(56), double(72)
+	latInc, lngInc := double(88), double(104)
+	sg := &subgrid{name: name}
+	sg.ll = lp{-wLng * d2r, sLat * d2r}
+	sg.del = lp{lngInc * d2r, latInc * d2r}
+	sg.cols = gridNodes(eLng, wLng, lngInc)
+	sg.rows = gridNodes(sLat, nLat, latInc)
+	if sg.cols < 1 || sg.rows < 1 || len(data) < headerSize+16*sg.cols*sg.rows {
+		return nil, ErrGridFormat
+	}
+
+	// rows run from south to north, each from east to west
+	sg.cvs = make([]float32, 2*sg.cols*sg.rows)
+	off := headerSize
+	for row := 0; row < sg.rows; row++ {
+		for i := 0; i < sg.cols; i++ {
+			j := 2 * (row*sg.cols + sg.cols - i - 1)
+			sg.cvs[j] = float32(double(off+8) * sec2rad)
+			sg.cvs[j+1] = float32(double(off) * sec2rad)
+			off += 16
+		}
+	}
+	return &grid{name: name, format: "ntv1", subgrids: []*subgrid{sg}}, nil
+}
+
+// gridNodes is how many nodes there are from lo to hi every inc, or 0 if
+// a corrupt header doesn't give a sensible number.
+func gridNodes(lo, hi, inc float64) int {
+	if !(inc > 0) || math.IsInf(inc, 0) {
+		return 0
+	}
+	n := math.Abs(hi-lo)/inc + 0.5
+	if !(n < 100000) {
+		return 0
+	}
+	return int(n) + 1
+}
+
+// parseCTable reads the original PROJ.4 binary format, a dump of the C
+// struct as written by nad2bin on a little endian 64 bit machine.
+func parseCTable(name string, data []byte) (*grid, error) {
+	const headerSize = 128
+	if len(data) < headerSize {
+		return nil, ErrGridFormat
+	}
+	return parseCTableData(name, "ctable", data[80:], data[headerSize:])
+}
+
+// parseCTable2 reads the CTable2 format, which is always little endian.
+func parseCTable2(name string, data []byte) (*grid, error) {
+	const headerSize = 160
+	if len(data) < headerSize {
+		return nil, ErrGridFormat
+	}
+	return parseCTableData(name, "ctable2", data[96:], data[headerSize:])
+}
+
+// parseCTableData reads the limits from hdr and the shifts from body, which
+// are already in radians and run west to east in rows from the south.
+func parseCTableData(name, format string, hdr, body []byte) (*grid, error) {
+	order := binary.LittleEndian
+	double := func(off int) float64 { return math.Float64frombits(order.Uint64(hdr[off:])) }
+	sg := &subgrid{name: name}
+	sg.ll = lp{double(0), double(8)}
+	sg.del = lp{double(16), double(24)}
+	sg.cols = int(int32(order.Uint32(hdr[32:])))
+	sg.rows = int(int32(order.Uint32(hdr[36:])))
+	if sg.cols < 1 || sg.cols > 100000 || sg.rows < 1 || sg.rows > 100000 {
+		return nil, ErrGridFormat
+	}
+	if len(body) < 8*sg.cols*sg.rows {
+		return nil, ErrGridFormat
+	}
+	sg.cvs = make([]float32, 2*sg.cols*sg.rows)
+	for i := range sg.cvs {
+		sg.cvs[i] = math.Float32frombits(order.Uint32(body[4*i:]))
+	}
+	return &grid{name: name, format: format, subgrids: []*subgrid{sg}}, nil
+}
@@ -0,0 +1,503 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// ntv2Subgrid describes a synthetic NTv2 subgrid, in seconds with longitudes
+// positive to the west as in the file
+type ntv2Subgrid struct {
+	name, parent           string
+	sLat, nLat, eLng, wLng float64
+	latInc, lngInc         float64
+	shift                  func(lng, lat float64) (dlng, dlat float64)
+}
+
+func writeNTv2(t *testing.T, order binary.ByteOrder, subgrids ...ntv2Subgrid) string {
+	var buf bytes.Buffer
+	label := func(s string) {
+		b := []byte(s + "        ")
+		buf.Write(b[:8])
+	}
+	integer := func(key string, v int) {
+		label(key)
+		binary.Write(&buf, order, int32(v))
+		buf.Write(make([]byte, 4))
+	}
+	double := func(key string, v float64) {
+		label(key)
+		binary.Write(&buf, order, v)
+	}
+	str := func(key, v string) {
+		label(key)
+		label(v)
+	}
+
+	integer("NUM_OREC", 11)
+	integer("NUM_SREC", 11)
+	integer("NUM_FILE", len(subgrids))
+	str("GS_TYPE", "SECONDS")
+	str("VERSION", "NTv2.0")
+	str("SYSTEM_F", "TEST")
+	str("SYSTEM_T", "TEST")
+	double("MAJOR_F", 6378206.4)
+	double("MINOR_F", 6356583.8)
+	double("MAJOR_T", 6378137.0)
+	double("MINOR_T", 6356752.314)
+	for _, sg := range subgrids {
+		cols := int((sg.wLng-sg.eLng)/sg.lngInc) + 1
+		rows := int((sg.nLat-sg.sLat)/sg.latInc) + 1
+		str("SUB_NAME", sg.name)
+		str("PARENT", sg.parent)
+		str("CREATED", "")
+		str("UPDATED", "")
+		double("S_LAT", sg.sLat)
+		double("N_LAT", sg.nLat)
+		double("E_LONG", sg.eLng)
+		double("W_LONG", sg.wLng)
+		double("LAT_INC", sg.latInc)
+		double("LONG_INC", sg.lngInc)
+		integer("GS_COUNT", cols*rows)
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				dlng, dlat := sg.shift(-(sg.eLng+float64(col)*sg.lngInc)/3600, (sg.sLat+float64(row)*sg.latInc)/3600)
+				binary.Write(&buf, order, []float32{float32(dlat), float32(dlng), 0, 0})
+			}
+		}
+	}
+	str("END", "")
+
+	path := filepath.Join(t.TempDir(), "test.gsb")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testSubgrids cover 2°W to 2°E and 0° to 2°N with a linear shift, with a
+// finer child over 0° to 1°E, 0° to 1°N with a constant one
+var testSubgrids = []ntv2Subgrid{
+	{
+		name: "PARENT", sLat: 0, nLat: 7200, eLng: -7200, wLng: 7200, latInc: 3600, lngInc: 3600,
+		shift: func(lng, lat float64) (float64, float64) { return 1 + 0.5*lng, 0.5 + 0.25*lat },
+	},
+	{
+		name: "CHILD", parent: "PARENT", sLat: 0, nLat: 3600, eLng: -3600, wLng: 0, latInc: 1800, lngInc: 1800,
+		shift: func(lng, lat float64) (float64, float64) { return -2, 3 },
+	},
+}
+
+func TestNTv2(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		path := writeNTv2(t, order, testSubgrids...)
+		src, err := NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst, _ := NewProjection("+proj=longlat +ellps=WGS84 +towgs84=0,0,0")
+
+		for _, pt := range [][4]float64{
+			// lng, lat, and the expected shift in seconds, west positive
+			{-1.5, 0.25, 1 + 0.5*-1.5, 0.5 + 0.25*0.25},
+			{1.75, 1.9, 1 + 0.5*1.75, 0.5 + 0.25*1.9},
+			{-2, 0.5, 0, 0.5 + 0.25*0.5},
+			{0.3, 0.6, -2, 3},
+		} {
+			lng0, lat0 := pt[0]*d2r, pt[1]*d2r
+			x, y := []float64{lng0}, []float64{lat0}
+			if err := Transform(src, dst, x, y, nil); err != nil {
+				t.Fatal(err)
+			}
+			expLng, expLat := lng0-pt[2]*sec2rad, lat0+pt[3]*sec2rad
+			if !within(expLng, x[0], 1.0e-12) || !within(expLat, y[0], 1.0e-12) {
+				t.Errorf("shift off at %v: (%.12f, %.12f) - (%.12f, %.12f)", pt, expLng, expLat, x[0], y[0])
+			}
+
+			if err := Transform(dst, src, x, y, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !within(lng0, x[0], 1.0e-12) || !within(lat0, y[0], 1.0e-12) {
+				t.Errorf("round trip off at %v: (%.12f, %.12f) - (%.12f, %.12f)", pt, lng0, lat0, x[0], y[0])
+			}
+		}
+
+		x, y := []float64{0, 10 * d2r}, []float64{0, 10 * d2r}
+		err = Transform(src, dst, x, y, nil)
+		var pe *ProjectionError
+		if !errors.Is(err, ErrOutsideGrid) || !errors.As(err, &pe) {
+			t.Errorf("expected ErrOutsideGrid, got %v", err)
+		} else if pe.Proj != "longlat" || pe.X != 10*d2r || pe.Y != 10*d2r {
+			t.Errorf("expected the point that failed, got %v", err)
+		}
+	}
+}
+
+func TestNadgridsList(t *testing.T) {
+	path := writeNTv2(t, binary.LittleEndian, testSubgrids...)
+	dst, _ := NewProjection("+proj=longlat +datum=WGS84")
+
+	// the null grid catches anything the others don't
+	src, _ := NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=@missing.gsb," + path + ",@null")
+	x, y := []float64{10 * d2r, 0.3 * d2r}, []float64{10 * d2r, 0.6 * d2r}
+	if err := Transform(src, dst, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !within(10*d2r, x[0], 1.0e-12) || !within(10*d2r, y[0], 1.0e-12) {
+		t.Errorf("null grid shifted: (%f, %f)", x[0], y[0])
+	}
+	if !within(0.3*d2r+2*sec2rad, x[1], 1.0e-12) || !within(0.6*d2r+3*sec2rad, y[1], 1.0e-12) {
+		t.Errorf("grid skipped: (%f, %f)", x[1], y[1])
+	}
+
+	src, _ = NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=missing.gsb")
+	if err := Transform(src, dst, x, y, nil); !errors.Is(err, ErrGridNotFound) {
+		t.Errorf("expected ErrGridNotFound, got %v", err)
+	}
+
+	// relative names are found through PROJ_LIB
+	t.Setenv("PROJ_LIB", filepath.Dir(path))
+	src, _ = NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=test.gsb")
+	x, y = []float64{0.3 * d2r}, []float64{0.6 * d2r}
+	if err := Transform(src, dst, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(y[0]-(0.6*d2r+3*sec2rad)) > 1.0e-12 {
+		t.Errorf("grid from PROJ_LIB not applied: %f", y[0])
+	}
+}
+
+// testShift is the shift, in seconds with longitude positive west, used by
+// the single grid formats below
+func TestNTv2Header(t *testing.T) {
+	data, err := os.ReadFile(writeNTv2(t, binary.LittleEndian, testSubgrids...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseNTv2("test.gsb", data); err != nil {
+		t.Fatal(err)
+	}
+
+	// a corrupt subgrid header mustn't size the grid, and certainly not
+	// past the end of the file
+	field := func(b []byte, i int) []byte { return b[11*16+i*16+8:] }
+	for _, test := range []struct {
+		name   string
+		fields map[int]float64
+		nodes  int
+	}{
+		{"zero LAT_INC", map[int]float64{8: 0}, 0},
+		{"NaN LONG_INC", map[int]float64{9: math.NaN()}, 0},
+		{"negative LONG_INC", map[int]float64{9: -3600}, 0},
+		// 64GB of records in a file of a few hundred bytes
+		{"huge extent", map[int]float64{5: 99998 * 3600, 7: -7200 + 39999*3600}, 99999 * 40000},
+	} {
+		corrupt := append([]byte(nil), data...)
+		for i, v := range test.fields {
+			binary.LittleEndian.PutUint64(field(corrupt, i), math.Float64bits(v))
+		}
+		binary.LittleEndian.PutUint32(field(corrupt, 10), uint32(test.nodes))
+		if _, err := parseNTv2("test.gsb", corrupt); err != ErrGridFormat {
+			t.Errorf("%s: expected ErrGridFormat, got %v", test.name, err)
+		}
+	}
+}
+
+func testShift(lng, lat float64) (dlng, dlat float64) {
+	return 0.5 - 0.1*lng, 1 + 0.02*lat
+}
+
+// writeNTv1 writes a grid covering lng0..lng0+2, lat0..lat0+2 degrees
+func writeNTv1(t *testing.T, path string, lng0, lat0 float64) {
+	var buf bytes.Buffer
+	record := func(key string, v interface{}) {
+		buf.Write([]byte(key + "        ")[:8])
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	record("HEADER", [2]int32{12, 0})
+	record("S_LAT", lat0)
+	record("N_LAT", lat0+2)
+	record("E_LONG", -(lng0 + 2))
+	record("W_LONG", -lng0)
+	record("LAT_INC", 1.0)
+	record("LONG_INC", 1.0)
+	for _, key := range []string{"TYPE", "VERSION", "DATUM_F", "DATUM_T"} {
+		record(key, [8]byte{})
+	}
+	for row := 0; row < 3; row++ {
+		for col := 2; col >= 0; col-- {
+			dlng, dlat := testShift(lng0+float64(col), lat0+float64(row))
+			binary.Write(&buf, binary.BigEndian, []float64{dlat, dlng})
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeCTable writes a grid covering lng0..lng0+2, lat0..lat0+2 degrees,
+// in either CTable format
+func writeCTable(t *testing.T, path string, v2 bool, lng0, lat0 float64) {
+	var buf bytes.Buffer
+	if v2 {
+		buf.Write([]byte("CTABLE V2\x00\x00\x00\x00\x00\x00\x00"))
+	}
+	buf.Write(make([]byte, 80))
+	binary.Write(&buf, binary.LittleEndian, []float64{lng0 * d2r, lat0 * d2r, d2r, d2r})
+	binary.Write(&buf, binary.LittleEndian, []int32{3, 3})
+	if v2 {
+		buf.Write(make([]byte, 24))
+	} else {
+		buf.Write(make([]byte, 8))
+	}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			dlng, dlat := testShift(lng0+float64(col), lat0+float64(row))
+			binary.Write(&buf, binary.LittleEndian, []float32{float32(dlng * sec2rad), float32(dlat * sec2rad)})
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNAD27Grids(t *testing.T) {
+	dir := t.TempDir()
+	writeCTable(t, filepath.Join(dir, "conus"), false, -100, 30)
+	writeCTable(t, filepath.Join(dir, "alaska"), true, -150, 60)
+	writeNTv1(t, filepath.Join(dir, "ntv1_can.dat"), -80, 45)
+	t.Setenv("PROJ_LIB", dir)
+
+	nad27, err := NewProjection("+proj=longlat +datum=NAD27")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	for _, ll := range [][2]float64{{-99.2, 31.7}, {-148.5, 60.1}, {-79.9, 46.3}} {
+		lng0, lat0 := ll[0]*d2r, ll[1]*d2r
+		x, y := []float64{lng0}, []float64{lat0}
+		if err := Transform(nad27, wgs84, x, y, nil); err != nil {
+			t.Fatal(err)
+		}
+		dlng, dlat := testShift(ll[0], ll[1])
+		expLng, expLat := lng0-dlng*sec2rad, lat0+dlat*sec2rad
+		if !within(expLng, x[0], 1.0e-11) || !within(expLat, y[0], 1.0e-11) {
+			t.Errorf("shift off at %v: (%.12f, %.12f) - (%.12f, %.12f)", ll, expLng, expLat, x[0], y[0])
+		}
+		if err := Transform(wgs84, nad27, x, y, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !within(lng0, x[0], 1.0e-11) || !within(lat0, y[0], 1.0e-11) {
+			t.Errorf("round trip off at %v: (%.12f, %.12f) - (%.12f, %.12f)", ll, lng0, lat0, x[0], y[0])
+		}
+	}
+
+	// nothing covers Europe
+	x, y := []float64{2 * d2r}, []float64{48 * d2r}
+	if err := Transform(nad27, wgs84, x, y, nil); !errors.Is(err, ErrOutsideGrid) {
+		t.Errorf("expected ErrOutsideGrid, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "junk"), []byte("not a grid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	junk, _ := NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=junk")
+	if err := Transform(junk, wgs84, x, y, nil); !errors.Is(err, ErrGridFormat) {
+		t.Errorf("expected ErrGridFormat, got %v", err)
+	}
+}
+
+func TestNTv1Header(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ntv1_can.dat")
+	writeNTv1(t, path, -80, 45)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := parseNTv1("ntv1_can.dat", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := g.subgrids[0]
+	if !within(-80*d2r, sg.ll.lam, 1e-15) || !within(45*d2r, sg.ll.phi, 1e-15) {
+		t.Errorf("expected the south west corner at (-80, 45), got (%f, %f)", sg.ll.lam/d2r, sg.ll.phi/d2r)
+	}
+	if !within(d2r, sg.del.lam, 1e-15) || !within(d2r, sg.del.phi, 1e-15) {
+		t.Errorf("expected 1 degree cells, got (%f, %f)", sg.del.lam/d2r, sg.del.phi/d2r)
+	}
+	if sg.cols != 3 || sg.rows != 3 {
+		t.Errorf("expected 3x3 nodes, got %dx%d", sg.cols, sg.rows)
+	}
+
+	// a corrupt header mustn't size the grid
+	for _, field := range []struct {
+		off int
+		v   float64
+	}{{88, -1}, {88, 0}, {104, math.NaN()}, {104, math.Inf(1)}, {40, 1e300}} {
+		corrupt := append([]byte(nil), data...)
+		binary.BigEndian.PutUint64(corrupt[field.off:], math.Float64bits(field.v))
+		if _, err := parseNTv1("ntv1_can.dat", corrupt); err != ErrGridFormat {
+			t.Errorf("%d = %g: expected ErrGridFormat, got %v", field.off, field.v, err)
+		}
+	}
+}
+
+// writeGTX writes a geoid grid from lng0, lat0 in 1 degree steps, with a
+// missing value at the north east corner
+func writeGTX(t *testing.T, path string, lng0, lat0 float64, rows, cols int, height func(lng, lat float64) float64) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, []float64{lat0, lng0, 1, 1})
+	binary.Write(&buf, binary.BigEndian, []int32{int32(rows), int32(cols)})
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			h := float32(height(lng0+float64(col), lat0+float64(row)))
+			if row == rows-1 && col == cols-1 {
+				h = gtxNoData
+			}
+			binary.Write(&buf, binary.BigEndian, h)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeoidgrids(t *testing.T) {
+	height := func(lng, lat float64) float64 { return 40 + 2*lng - 0.5*lat }
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.gtx")
+	writeGTX(t, path, -2, 50, 4, 5, height)
+	// the same grid, but with its origin given from 0 to 360
+	path360 := filepath.Join(dir, "test360.gtx")
+	writeGTX(t, path360, 358, 50, 4, 5, func(lng, lat float64) float64 { return height(lng-360, lat) })
+
+	ellps, _ := NewProjection("+proj=longlat +datum=WGS84")
+	for _, p := range []string{path, "@missing.gtx," + path360} {
+		geoid, err := NewProjection("+proj=longlat +datum=WGS84 +geoidgrids=" + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lng, lat := -0.25, 51.5
+		x, y, z := []float64{lng * d2r}, []float64{lat * d2r}, []float64{100}
+		if err := Transform(geoid, ellps, x, y, z); err != nil {
+			t.Fatal(err)
+		}
+		if !within(100+height(lng, lat), z[0], 1.0e-5) {
+			t.Errorf("%s: expected ellipsoidal height %f, got %f", p, 100+height(lng, lat), z[0])
+		}
+		if !within(lng*d2r, x[0], 1.0e-12) || !within(lat*d2r, y[0], 1.0e-12) {
+			t.Errorf("%s: geoid moved the point: (%f, %f)", p, x[0], y[0])
+		}
+		if err := Transform(ellps, geoid, x, y, z); err != nil {
+			t.Fatal(err)
+		}
+		if !within(100, z[0], 1.0e-5) {
+			t.Errorf("%s: expected orthometric height 100, got %f", p, z[0])
+		}
+
+		// outside the grid, and next to the missing value
+		for _, ll := range [][2]float64{{10, 51}, {1.5, 52.5}} {
+			x, y, z = []float64{ll[0] * d2r}, []float64{ll[1] * d2r}, []float64{0}
+			if err := Transform(geoid, ellps, x, y, z); !errors.Is(err, ErrOutsideGrid) {
+				t.Errorf("%s: expected ErrOutsideGrid at %v, got %v", p, ll, err)
+			}
+		}
+	}
+
+	// geoid grids can't shift datums, and vice versa
+	bad, _ := NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=" + path)
+	if err := Transform(bad, ellps, []float64{0}, []float64{0.9}, nil); !errors.Is(err, ErrGridFormat) {
+		t.Errorf("expected ErrGridFormat, got %v", err)
+	}
+	bad, _ = NewProjection("+proj=longlat +datum=WGS84 +geoidgrids=null")
+	if err := Transform(bad, ellps, []float64{0}, []float64{0.9}, nil); !errors.Is(err, ErrGridFormat) {
+		t.Errorf("expected ErrGridFormat, got %v", err)
+	}
+}
+
+func TestGridProvider(t *testing.T) {
+	path := writeNTv2(t, binary.LittleEndian, testSubgrids...)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, _ := NewProjection("+proj=longlat +datum=WGS84")
+	shifted := func(src Projection) error {
+		x, y := []float64{0.3 * d2r}, []float64{0.6 * d2r}
+		if err := Transform(src, dst, x, y, nil); err != nil {
+			return err
+		}
+		if !within(0.3*d2r+2*sec2rad, x[0], 1.0e-12) || !within(0.6*d2r+3*sec2rad, y[0], 1.0e-12) {
+			t.Errorf("grid not applied: (%f, %f)", x[0], y[0])
+		}
+		return nil
+	}
+	defer SetGridProvider(nil)
+
+	before, _ := NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=grids/embedded.gsb")
+	fsys := fstest.MapFS{"grids/embedded.gsb": &fstest.MapFile{Data: data}}
+	SetGridProvider(FSGridProvider{fsys})
+	src, _ := NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=grids/embedded.gsb")
+	if err := shifted(src); err != nil {
+		t.Fatal(err)
+	}
+
+	// projections keep the provider they were made with
+	if err := shifted(before); !errors.Is(err, ErrGridNotFound) {
+		t.Errorf("expected ErrGridNotFound, got %v", err)
+	}
+
+	// once loaded, grids come from the cache
+	delete(fsys, "grids/embedded.gsb")
+	if err := shifted(src); err != nil {
+		t.Fatal(err)
+	}
+
+	SetGridProvider(DirGridProvider{"/nonexistent", filepath.Dir(path)})
+	src, _ = NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=" + filepath.Base(path))
+	if err := shifted(src); err != nil {
+		t.Fatal(err)
+	}
+	src, _ = NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=grids/embedded.gsb")
+	if err := shifted(src); !errors.Is(err, ErrGridNotFound) {
+		t.Errorf("expected ErrGridNotFound, got %v", err)
+	}
+
+	// providers may say a grid is missing with their own errors
+	for _, missing := range []error{fs.ErrNotExist, &fs.PathError{Op: "open", Path: "missing.gsb", Err: fs.ErrNotExist},
+		fmt.Errorf("grid service: %w", ErrGridNotFound)} {
+		SetGridProvider(openGridFunc(func(name string) (io.ReadCloser, error) {
+			if name == filepath.Base(path) {
+				return os.Open(path)
+			}
+			return nil, missing
+		}))
+		src, _ = NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=@missing.gsb,@" + filepath.Base(path))
+		if err := shifted(src); err != nil {
+			t.Errorf("%v: %v", missing, err)
+		}
+		src, _ = NewProjection("+proj=longlat +ellps=clrk66 +nadgrids=missing.gsb")
+		if err := shifted(src); !errors.Is(err, missing) {
+			t.Errorf("expected %v, got %v", missing, err)
+		}
+	}
+}
+
+type openGridFunc func(name string) (io.ReadCloser, error)
+
+func (f openGridFunc) OpenGrid(name string) (io.ReadCloser, error) {
+	return f(name)
+}
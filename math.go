@@ -5,7 +5,6 @@
 package projectron
 
 import "math"
-import "errors"
 
 const (
 	s_pi    float64 = 3.14159265359
@@ -30,6 +29,7 @@ func msfn(sinphi, cosphi, es float64) float64 {
 // }
 
 func tsfn(phi, sinphi, e float64) float64 {
+	sinphi *= e
 	return math.Tan(.5*(half_pi-phi)) / math.Pow((1-sinphi)/(1+sinphi), .5*e)
 }
 
@@ -61,7 +61,7 @@ func phi2(e, ts float64) (float64, error) {
 			return phi, nil
 		}
 	}
-	return math.Inf(-1), errors.New("phi2 has no convergence")
+	return math.Inf(-1), noConvergence("phi2")
 }
 
 // #define SPI     3.14159265359
@@ -94,3 +94,248 @@ func sign(x float64) float64 {
 		return 1
 	}
 }
+
+// pj_enfn(double es) computes the coefficients of the meridional distance
+// series used by mlfn and invMlfn.
+
+func enfn(es float64) [5]float64 {
+	const (
+		c00 = 1.
+		c02 = .25
+		c04 = .046875
+		c06 = .01953125
+		c08 = .01068115234375
+		c22 = .75
+		c44 = .46875
+		c46 = .01302083333333333333
+		c48 = .00712076822916666666
+		c66 = .36458333333333333333
+		c68 = .00569661458333333333
+		c88 = .3076171875
+	)
+	var en [5]float64
+	en[0] = c00 - es*(c02+es*(c04+es*(c06+es*c08)))
+	en[1] = es * (c22 - es*(c04+es*(c06+es*c08)))
+	t := es * es
+	en[2] = t * (c44 - es*(c46+es*c48))
+	t *= es
+	en[3] = t * (c66 - es*c68)
+	en[4] = t * es * c88
+	return en
+}
+
+// pj_mlfn(double phi, double sphi, double cphi, double *en) {
+// 	cphi *= sphi;
+// 	sphi *= sphi;
+// 	return(en[0] * phi - cphi * (en[1] + sphi*(en[2]
+// 		+ sphi*(en[3] + sphi*en[4]))));
+// }
+
+func mlfn(phi, sphi, cphi float64, en [5]float64) float64 {
+	cphi *= sphi
+	sphi *= sphi
+	return en[0]*phi - cphi*(en[1]+sphi*(en[2]+sphi*(en[3]+sphi*en[4])))
+}
+
+func invMlfn(arg, es float64, en [5]float64) (float64, error) {
+	k := 1 / (1 - es)
+	phi := arg
+	for i := 0; i < 10; i++ {
+		s := math.Sin(phi)
+		t := 1 - es*s*s
+		t = (mlfn(phi, s, math.Cos(phi), en) - arg) * (t * math.Sqrt(t)) * k
+		phi -= t
+		if math.Abs(t) < 1e-11 {
+			return phi, nil
+		}
+	}
+	return math.Inf(-1), noConvergence("invMlfn")
+}
+
+// gatg evaluates the Clenshaw summation of a real trig series, used to
+// move between geodetic and Gaussian latitudes.
+func gatg(p []float64, b float64) float64 {
+	cos2B := 2 * math.Cos(2*b)
+	var h, h2 float64
+	h1 := p[len(p)-1]
+	for i := len(p) - 2; i >= 0; i-- {
+		h = -h2 + cos2B*h1 + p[i]
+		h2 = h1
+		h1 = h
+	}
+	return b + h*math.Sin(2*b)
+}
+
+// clens is the real Clenshaw summation of a sine series.
+func clens(a []float64, argR float64) float64 {
+	r := 2 * math.Cos(argR)
+	var hr1, hr2 float64
+	hr := a[len(a)-1]
+	for i := len(a) - 2; i >= 0; i-- {
+		hr2 = hr1
+		hr1 = hr
+		hr = -hr2 + r*hr1 + a[i]
+	}
+	return math.Sin(argR) * hr
+}
+
+// clenS is the complex Clenshaw summation of a sine series, returning the
+// real and imaginary parts.
+func clenS(a []float64, argR, argI float64) (re, im float64) {
+	sinR, cosR := math.Sin(argR), math.Cos(argR)
+	sinhI, coshI := math.Sinh(argI), math.Cosh(argI)
+	r := 2 * cosR * coshI
+	i := -2 * sinR * sinhI
+	var hr1, hr2, hi, hi1, hi2 float64
+	hr := a[len(a)-1]
+	for j := len(a) - 2; j >= 0; j-- {
+		hr2, hi2 = hr1, hi1
+		hr1, hi1 = hr, hi
+		hr = -hr2 + r*hr1 - i*hi1 + a[j]
+		hi = -hi2 + i*hr1 + r*hi1
+	}
+	r = sinR * coshI
+	i = cosR * sinhI
+	return r*hr - i*hi, r*hi + i*hr
+}
+
+// gauss holds the constants of the conformal mapping of the ellipsoid onto
+// the Gaussian sphere used by the oblique (double) stereographic.
+type gauss struct {
+	c, k, e, ratexp float64
+}
+
+func srat(esinp, ratexp float64) float64 {
+	return math.Pow((1-esinp)/(1+esinp), ratexp)
+}
+
+// newGauss returns the mapping for an ellipsoid with eccentricity e about
+// phi0, along with the conformal latitude of phi0 and the radius of the
+// sphere in units of a.
+func newGauss(e, phi0 float64) (g *gauss, chi, rc float64, err error) {
+	es := e * e
+	sphi := math.Sin(phi0)
+	cphi := math.Cos(phi0)
+	cphi *= cphi
+	rc = math.Sqrt(1-es) / (1 - es*sphi*sphi)
+	g = &gauss{e: e}
+	g.c = math.Sqrt(1 + es*cphi*cphi/(1-es))
+	if g.c == 0 {
+		return nil, 0, 0, paramError("lat_0", ErrInvalidParam)
+	}
+	chi = math.Asin(sphi / g.c)
+	g.ratexp = 0.5 * g.c * e
+	sr := srat(e*sphi, g.ratexp)
+	if sr == 0 {
+		return nil, 0, 0, paramError("lat_0", ErrInvalidParam)
+	}
+	if .5*phi0+fort_pi < 1e-10 {
+		g.k = 1 / sr
+	} else {
+		g.k = math.Tan(.5*chi+fort_pi) / (math.Pow(math.Tan(.5*phi0+fort_pi), g.c) * sr)
+	}
+	return g, chi, rc, nil
+}
+
+func (g *gauss) fwd(lam, phi float64) (float64, float64) {
+	phi = 2*math.Atan(g.k*math.Pow(math.Tan(.5*phi+fort_pi), g.c)*srat(g.e*math.Sin(phi), g.ratexp)) - half_pi
+	return g.c * lam, phi
+}
+
+func (g *gauss) inv(lam, phi float64) (float64, float64, error) {
+	num := math.Pow(math.Tan(.5*phi+fort_pi)/g.k, 1/g.c)
+	lam /= g.c
+	for i := 0; i < 20; i++ {
+		elp := 2*math.Atan(num*srat(g.e*math.Sin(phi), -.5*g.e)) - half_pi
+		if math.Abs(elp-phi) < 1e-14 {
+			return lam, elp, nil
+		}
+		phi = elp
+	}
+	return hugeVal, hugeVal, noConvergence("gauss")
+}
+
+// pj_qsfn(double sinphi, double e, double one_es) {
+// 	double con, div1, div2;
+// 	if (e >= EPSILON) {
+// 		con = e * sinphi;
+// 		div1 = 1.0 - con * con;
+// 		div2 = 1.0 + con;
+// 		if (div1 == 0.0 || div2 == 0.0)
+// 			return HUGE_VAL;
+// 		return (one_es * (sinphi / div1 - (.5 / e) * log ((1. - con) / div2 )));
+// 	} else
+// 		return (sinphi + sinphi);
+// }
+
+func qsfn(sinphi, e, oneEs float64) float64 {
+	if e < 1.0e-7 {
+		return sinphi + sinphi
+	}
+	con := e * sinphi
+	div1 := 1 - con*con
+	div2 := 1 + con
+	if div1 == 0 || div2 == 0 {
+		return hugeVal
+	}
+	return oneEs * (sinphi/div1 - (.5/e)*math.Log((1-con)/div2))
+}
+
+// authset computes the coefficients of the series for converting authalic
+// latitudes back to geodetic ones.
+func authset(es float64) [3]float64 {
+	const (
+		p00 = .33333333333333333333
+		p01 = .17222222222222222222
+		p02 = .10257936507936507936
+		p10 = .06388888888888888888
+		p11 = .06640211640211640211
+		p20 = .01641501294219154443
+	)
+	var apa [3]float64
+	apa[0] = es * p00
+	t := es * es
+	apa[0] += t * p01
+	apa[1] = t * p10
+	t *= es
+	apa[0] += t * p02
+	apa[1] += t * p11
+	apa[2] = t * p20
+	return apa
+}
+
+// authlat returns the geodetic latitude of the authalic latitude beta.
+func authlat(beta float64, apa [3]float64) float64 {
+	t := beta + beta
+	return beta + apa[0]*math.Sin(t) + apa[1]*math.Sin(t+t) + apa[2]*math.Sin(t+t+t)
+}
+
+// authlatq refines the series in authlat with Newton's method on qsfn,
+// where qp is qsfn at the pole.  The series alone drifts by several
+// tenths of a millimetre at high latitudes.
+func authlatq(beta float64, apa [3]float64, e, oneEs, qp float64) float64 {
+	phi := authlat(beta, apa)
+	q := qp * math.Sin(beta)
+	for i := 0; i < 5; i++ {
+		sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+		if math.Abs(cosphi) < epsln {
+			break
+		}
+		con := e * sinphi
+		com := 1 - con*con
+		dphi := .5 * com * com / cosphi * (q/oneEs - sinphi/com + .5/e*math.Log((1-con)/(1+con)))
+		phi += dphi
+		if math.Abs(dphi) < 1.0e-14 {
+			break
+		}
+	}
+	return phi
+}
+
+// aasin is asin, tolerant of arguments a hair outside of [-1, 1].
+func aasin(v float64) float64 {
+	if math.Abs(v) >= 1 {
+		return math.Copysign(half_pi, v)
+	}
+	return math.Asin(v)
+}
@@ -0,0 +1,148 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// everyImpl has a definition for each name lookupImpl knows.
+var everyImpl = map[string]string{
+	"latlong": "+proj=latlong +datum=WGS84",
+	"longlat": "+proj=longlat +ellps=clrk66 +towgs84=-8,160,176 +axis=neu",
+	"latlon":  "+proj=latlon +ellps=airy +pm=paris",
+	"lonlat":  "+proj=lonlat +datum=WGS84 +lon_wrap=180",
+	"merc":    "+proj=merc +lat_ts=30 +lon_0=10 +ellps=GRS80",
+	"lcc":     "+proj=lcc +lat_1=33 +lat_2=45 +lat_0=23 +lon_0=-96 +datum=NAD83",
+	"eqc":     "+proj=eqc +lat_ts=30 +lon_0=10 +ellps=WGS84",
+	"tmerc":   "+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +datum=OSGB36",
+	"utm":     "+proj=utm +zone=14 +datum=WGS84 +units=us-ft",
+	"stere":   "+proj=stere +lat_0=90 +lat_ts=70 +lon_0=-45 +datum=WGS84",
+	"sterea":  "+proj=sterea +lat_0=52.15616055555555 +lon_0=5.38763888888889 +k=0.9999079 +x_0=155000 +y_0=463000 +ellps=bessel",
+	"somerc":  "+proj=somerc +lat_0=46.95240555555556 +lon_0=7.439583333333333 +k_0=1 +x_0=2600000 +y_0=1200000 +ellps=bessel",
+	"aea":     "+proj=aea +lat_1=29.5 +lat_2=45.5 +lat_0=37.5 +lon_0=-96 +datum=NAD83",
+	"laea":    "+proj=laea +lat_0=52 +lon_0=10 +x_0=4321000 +y_0=3210000 +ellps=GRS80",
+	"aeqd":    "+proj=aeqd +lat_0=40 +lon_0=-100 +ellps=WGS84",
+	"ortho":   "+proj=ortho +lat_0=40 +lon_0=-100 +R=6371000",
+	"gnom":    "+proj=gnom +lat_0=40 +lon_0=-100 +R=6371000",
+	"geocent": "+proj=geocent +datum=WGS84",
+}
+
+// concurrentPoints are lng/lat in radians around North America, which
+// every projection in everyImpl can see.
+func concurrentPoints(n int) (lng, lat []float64) {
+	lng, lat = make([]float64, n), make([]float64, n)
+	for i := range lng {
+		lng[i] = (-110 + 20*float64(i%83)/83) * d2r
+		lat[i] = (40 + 15*float64(i%71)/71) * d2r
+	}
+	return lng, lat
+}
+
+func TestConcurrentProjections(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	for name, defn := range everyImpl {
+		if lookupImpl(&pj{proj: name}) == nil {
+			t.Errorf("%s isn't a projection", name)
+		}
+		proj, err := NewProjection(defn)
+		if err != nil {
+			t.Fatalf("%s: %v", defn, err)
+		}
+
+		// what it should do, one at a time
+		lng, lat := concurrentPoints(200)
+		expX, expY := append([]float64(nil), lng...), append([]float64(nil), lat...)
+		expZ := make([]float64, len(lng))
+		if err := Transform(wgs84, proj, expX, expY, expZ); err != nil {
+			t.Fatalf("%s: %v", defn, err)
+		}
+		expDefn := definition(proj)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				x, y := append([]float64(nil), lng...), append([]float64(nil), lat...)
+				z := make([]float64, len(lng))
+				if err := Transform(wgs84, proj, x, y, z); err != nil {
+					t.Errorf("%s: %v", defn, err)
+					return
+				}
+				for i := range x {
+					if x[i] != expX[i] || y[i] != expY[i] || z[i] != expZ[i] {
+						t.Errorf("%s: point %d differs", defn, i)
+						return
+					}
+				}
+				if _, ok := proj.(*Geocentric); !ok {
+					for i := range lng {
+						x, y, _ := proj.Forward(lng[i], lat[i])
+						proj.Inverse(x, y)
+					}
+					x, y = append([]float64(nil), lng...), append([]float64(nil), lat...)
+					ForwardSlice(proj, x, y)
+					InverseSlice(proj, x, y)
+				}
+				if definition(proj) != expDefn {
+					t.Errorf("%s: definition changed", defn)
+				}
+				FormatWKT2(proj)
+				FormatPROJJSON(proj)
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestTransformParallel(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	for name, defn := range everyImpl {
+		proj, err := NewProjection(defn)
+		if err != nil {
+			t.Fatalf("%s: %v", defn, err)
+		}
+		lng, lat := concurrentPoints(3*minShard + 17)
+		expX, expY := append([]float64(nil), lng...), append([]float64(nil), lat...)
+		expZ := make([]float64, len(lng))
+		if err := Transform(wgs84, proj, expX, expY, expZ); err != nil {
+			t.Fatalf("%s: %v", defn, err)
+		}
+
+		for _, workers := range []int{0, 1, 2, 3, 4, 1000} {
+			x, y := append([]float64(nil), lng...), append([]float64(nil), lat...)
+			z := make([]float64, len(lng))
+			if err := TransformParallel(wgs84, proj, x, y, z, workers); err != nil {
+				t.Fatalf("%s with %d workers: %v", name, workers, err)
+			}
+			for i := range x {
+				if x[i] != expX[i] || y[i] != expY[i] || z[i] != expZ[i] {
+					t.Fatalf("%s with %d workers: point %d differs", name, workers, i)
+				}
+			}
+		}
+	}
+
+	// the errors come back
+	ortho, _ := NewProjection(everyImpl["ortho"])
+	lng, lat := concurrentPoints(4 * minShard)
+	lng[3*minShard] = 80 * d2r
+	if err := TransformParallel(wgs84, ortho, lng, lat, nil, 4); !errors.Is(err, ErrPointNotVisible) {
+		t.Errorf("expected ErrPointNotVisible, got %v", err)
+	}
+	if err := TransformParallel(wgs84, ortho, lng, lat[1:], nil, 4); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam, got %v", err)
+	}
+	geocent, _ := NewProjection(everyImpl["geocent"])
+	if err := TransformParallel(geocent, wgs84, lng, lat, nil, 4); !errors.Is(err, ErrGeocentric) {
+		t.Errorf("expected ErrGeocentric, got %v", err)
+	}
+	if err := TransformParallel(wgs84, geocent, lng, lat, nil, 4); !errors.Is(err, ErrGeocentric) {
+		t.Errorf("expected ErrGeocentric, got %v", err)
+	}
+}
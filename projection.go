@@ -5,12 +5,15 @@
 package projectron
 
 import (
-	"errors"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// Projection is a coordinate reference system made by NewProjection or one
+// of its siblings.  Projections are never changed once they're made, so
+// one can be used from any number of goroutines at once.
 type Projection interface {
 	// Forward projects lng/lat into this.  l/l are in radians
 	Forward(lng, lat float64) (x, y float64, err error)
@@ -18,13 +21,67 @@ type Projection interface {
 	Inverse(x, y float64) (lng, lat float64, err error)
 	IsLngLat() bool
 	ToMeter() float64
+	// FromGreenwich is the longitude of the prime meridian, in radians
 	FromGreenwich() float64
 	Radius() float64
 }
 
+// Definer describes a projection as a proj4 string.  Every Projection this
+// package makes is one.  Definition is kept off Projection so that adding
+// it didn't break implementations of Projection outside this package, and
+// so that the interface they implement stays stable.
+type Definer interface {
+	// Definition returns a normalised proj4 string for this, with the datum
+	// and ellipsoid expanded and the units resolved.  Equivalent
+	// definitions give the same string.
+	Definition() string
+}
+
+// BatchProjector projects many points in place at once.  Every Projection
+// this package makes is one, and the package's batch functions use it when
+// they're given one.  A projection with nothing to gain from a batch
+// needn't have the methods, which is why they aren't on Projection.
+type BatchProjector interface {
+	// ForwardSlice projects the points in lng and lat in place
+	ForwardSlice(lng, lat []float64) error
+	// InverseSlice projects the points in x and y back to lng/lat in place
+	InverseSlice(x, y []float64) error
+	// ForwardStrided projects the points in coords in place, which start
+	// every stride values with lng then lat; 2 for interleaved pairs, 3
+	// if they come with heights
+	ForwardStrided(coords []float64, stride int) error
+	// InverseStrided is the inverse of ForwardStrided
+	InverseStrided(coords []float64, stride int) error
+}
+
+// GeocentricConverter converts between geodetic and geocentric coordinates
+// on a projection's ellipsoid.  Every Projection this package makes is one.
+type GeocentricConverter interface {
+	// ToGeocentric converts lng/lat (in radians) and ellipsoidal height into
+	// Earth-centred, Earth-fixed XYZ on this projection's ellipsoid
+	ToGeocentric(lng, lat, h float64) (x, y, z float64, err error)
+	// FromGeocentric converts Earth-centred, Earth-fixed XYZ back into
+	// lng/lat and ellipsoidal height
+	FromGeocentric(x, y, z float64) (lng, lat, h float64)
+}
+
+// NewProjection sets up a projection from a proj4 string, or an EPSG code
+// like "EPSG:4326", which may also be given as +init=epsg:4326.  When it
+// can't, the error is a *ProjectionError naming the parameter that's wrong.
 func NewProjection(str string) (Projection, error) {
+	if _, ok := parseEPSGCode(str); ok {
+		str = "+init=" + str
+	}
+	parms := parseParams(str)
+	if err := parms.expandInit(); err != nil {
+		return nil, paramError("init", err)
+	}
+	return newProjection(parms)
+}
+
+// parseParams splits a proj4 string into its parameters.
+func parseParams(str string) paramset {
 	parms := make(paramset)
-	var ok bool
 	for _, part := range strings.Split(str, "+") {
 		param := strings.TrimSpace(part)
 		if param == "" {
@@ -33,11 +90,20 @@ func NewProjection(str string) (Projection, error) {
 		key, val := keyVal(param)
 		parms[key] = val
 	}
+	return parms
+}
+
+// newProjection sets up the projection described by parms, however they
+// were written.
+func newProjection(parms paramset) (Projection, error) {
+	var ok bool
 	pin := &pj{axis: "enu"}
 	if pin.proj, ok = parms.string("proj"); !ok {
-		return nil, ErrUnsupportedProj
+		return nil, &ProjectionError{Code: ErrCodeMissingArg, Param: "proj", Err: ErrUnsupportedProj}
+	}
+	if err := pin.setDatum(parms); err != nil {
+		return nil, pin.paramError("towgs84", err)
 	}
-	pin.setDatum(parms)
 	pin.setEllipse(parms)
 
 	pin.aOrig = pin.a
@@ -54,6 +120,11 @@ func NewProjection(str string) (Projection, error) {
 		pin.datumType = PJD_WGS84
 	}
 
+	if geoidgrids, ok := parms.string("geoidgrids"); ok {
+		pin.geoidgrids = geoidgrids
+		pin.gridSource = currentGridSource()
+	}
+
 	pin.geoc, _ = parms.bool("geoc")
 	pin.over, _ = parms.bool("over")
 
@@ -63,10 +134,9 @@ func NewProjection(str string) (Projection, error) {
 	}
 
 	if axis, ok := parms.string("axis"); ok {
-		if len(axis) != 3 {
-			return nil, ErrInvalidParam
+		if !validAxis(axis) {
+			return nil, pin.paramError("axis", ErrInvalidParam)
 		}
-		// TODO: validate (I'm in a hurry)
 		pin.axis = axis
 	}
 
@@ -88,55 +158,46 @@ func NewProjection(str string) (Projection, error) {
 		pin.k0 = 1.
 	}
 	if pin.k0 <= 0 {
-		return nil, ErrInvalidParam
+		return nil, pin.paramError("k_0", ErrInvalidParam)
 	}
 
-	// units
-	var s string
-	if name, ok := parms.string("units"); ok {
-		if unit, ok := units_list[name]; ok {
-			pin.to_meter = unit.to_meter
-			pin.fr_meter = 1 / unit.to_meter
-		}
-	} else {
-		s, _ = parms.string("to_meter")
-	}
-	if s != "" {
-		// do something about the to_meter thing
-	} else {
-		pin.to_meter = 1
-		pin.fr_meter = 1
-	}
-	// vertical units
-	s = ""
-	if name, ok := parms.string("vunits"); ok {
-		if unit, ok := units_list[name]; ok {
-			pin.to_meter = unit.to_meter
-			pin.fr_meter = unit.to_meter
+	// units, which don't apply to lng/lat
+	var err error
+	pin.to_meter = 1
+	if !isLngLat(pin.proj) {
+		if pin.to_meter, err = parms.units("units", "to_meter", 1); err != nil {
+			return nil, pin.paramError("units", err)
 		}
-	} else {
-		s, _ = parms.string("vto_meter")
 	}
-	if s != "" {
-		// do something about the to_meter thing
-	} else {
-		pin.vto_meter = pin.to_meter
-		pin.vfr_meter = pin.fr_meter
+	pin.fr_meter = 1 / pin.to_meter
+	// vertical units, which are the horizontal ones unless they're given
+	if pin.vto_meter, err = parms.units("vunits", "vto_meter", pin.to_meter); err != nil {
+		return nil, pin.paramError("vunits", err)
 	}
+	pin.vfr_meter = 1 / pin.vto_meter
 
-	// prime meridian
-	if name, ok := parms.string("pm"); ok {
-		if pm, ok := pm_list[name]; ok {
-			pin.from_greenwich = parseDegreeString(pm.defn)
+	// prime meridian, by name or in degrees
+	if pm, ok := parms.string("pm"); ok {
+		if named, ok := pm_list[pm]; ok {
+			pm = named.defn
+		} else if _, err := strconv.ParseFloat(strings.SplitN(pm, "d", 2)[0], 64); err != nil {
+			return nil, pin.paramError("pm", ErrInvalidParam)
 		}
+		pin.from_greenwich = parseDegreeString(pm) * d2r
 	}
 
 	imp := lookupImpl(pin)
 	if imp != nil {
-		imp.init(parms)
+		if err := imp.init(parms); err != nil {
+			return nil, pin.paramError("", err)
+		}
+		pin.params = parms
+		if t, ok := imp.(translators); ok {
+			pin.forward, pin.inverse = t.fwd, t.inv
+		}
 		return imp, nil
 	}
-	return nil, ErrUnsupportedProj
+	return nil, pin.paramError("proj", ErrUnsupportedProj)
 }
 
 type pj struct {
@@ -144,6 +205,9 @@ type pj struct {
 	axis                 string
 	datumType            datumType
 	datumParams          []float64
+	nadgrids             string
+	geoidgrids           string
+	gridSource           *gridSource
 	catalogName          string
 	a, es, e, ra         float64
 	lam0, phi0, k0       float64
@@ -156,7 +220,13 @@ type pj struct {
 	to_meter, fr_meter   float64
 	vto_meter, vfr_meter float64
 	from_greenwich       float64
-
+	// params are what the projection was made from, with the datum and
+	// ellipsoid expanded
+	params paramset
+	// forward and inverse are the implementation's translators, for the
+	// batch methods, or nil if it doesn't project through commonFwd and
+	// commonInv
+	forward, inverse translator
 }
 
 func (p *pj) setDatum(params paramset) error {
@@ -168,15 +238,19 @@ func (p *pj) setDatum(params paramset) error {
 		}
 	}
 
-	if _, ok := params.string("nadgrids"); ok {
-		// BUG(slecuyer): no support for nadgrids
+	if nadgrids, ok := params.string("nadgrids"); ok {
 		p.datumType = PJD_GRIDSHIFT
+		p.nadgrids = nadgrids
+		p.gridSource = currentGridSource()
 	} else if catalog, ok := params.string("catalog"); ok {
 		p.datumType = PJD_GRIDSHIFT
 		p.catalogName = catalog
 		// BUG(slecuyer): we don't do anything with the catalog date
 	} else if towgs84, ok := params.string("towgs84"); ok {
 		parts := strings.Split(towgs84, ",")
+		if len(parts) != 3 && len(parts) != 7 {
+			return ErrInvalidParam
+		}
 		p.datumParams = make([]float64, 7)
 		for i, f := range parts {
 			p.datumParams[i], _ = strconv.ParseFloat(f, 64)
@@ -245,50 +319,157 @@ func (p *pj) setEllipse(params paramset) error {
 	return nil
 }
 
+// projector is what commonFwd and commonInv need from p besides the point,
+// worked out once, so the batch functions don't redo it for every point.
+type projector struct {
+	*pj
+	tr translator
+	// shift is the prime meridian and central meridian together
+	shift float64
+	enu   bool
+}
+
+func (p *pj) projector(tr translator) projector {
+	return projector{pj: p, tr: tr, shift: p.from_greenwich + p.lam0, enu: p.axis == "enu"}
+}
+
 func (p *pj) commonFwd(lam, phi float64, tr translator) (x, y float64, err error) {
-	// println(p.to_meter, p.fr_meter, p.a)
+	pr := p.projector(tr)
+	return pr.fwd(lam, phi)
+}
+
+func (p *pj) commonInv(x, y float64, tr translator) (lam, phi float64, err error) {
+	pr := p.projector(tr)
+	return pr.inv(x, y)
+}
+
+func (pr *projector) fwd(lam, phi float64) (x, y float64, err error) {
+	p := pr.pj
+	lng, lat := lam, phi
 	t := math.Abs(phi) - half_pi
 	if t > epsln || math.Abs(lam) > 10 {
-		return hugeVal, hugeVal, errors.New("this is way out of bounds")
+		return hugeVal, hugeVal, p.coordError(ErrInvalidCoord, lng, lat)
 	}
 	if math.Abs(t) <= epsln {
 		phi = math.Copysign(half_pi, phi)
 	} else if p.geoc {
 		phi = math.Atan(p.rOneEs * math.Tan(phi))
 	}
-	lam -= p.lam0
+	lam -= pr.shift
 	if !p.over {
 		lam = adjLng(lam)
 	}
-	x, y, err = tr(lam, phi)
+	x, y, err = pr.tr(lam, phi)
 	if err != nil {
-		return hugeVal, hugeVal, err
+		return hugeVal, hugeVal, p.coordError(err, lng, lat)
 	}
 	x = p.fr_meter * (p.a*x + p.x0)
 	y = p.fr_meter * (p.a*y + p.y0)
+	if !pr.enu {
+		x, y = p.toAxis(x, y)
+	}
 	return
 }
 
-func (p *pj) commonInv(x, y float64, tr translator) (lam, phi float64, err error) {
+func (pr *projector) inv(x, y float64) (lam, phi float64, err error) {
+	p := pr.pj
 	if x == hugeVal || y == hugeVal {
-		return hugeVal, hugeVal, errors.New("this is way out of bounds")
+		return hugeVal, hugeVal, p.coordError(ErrInvalidCoord, x, y)
+	}
+	inX, inY := x, y
+	if !pr.enu {
+		x, y = p.fromAxis(x, y)
 	}
 	x = (x*p.to_meter - p.x0) * p.ra
 	y = (y*p.to_meter - p.y0) * p.ra
-	lam, phi, err = tr(x, y)
+	lam, phi, err = pr.tr(x, y)
 	if err != nil {
-		return hugeVal, hugeVal, err
-	}
-	y += p.lam0
-	if !p.over {
-		x = adjLng(x)
+		return hugeVal, hugeVal, p.coordError(err, inX, inY)
 	}
+	lam = p.wrapLng(lam + pr.shift)
 	if p.geoc && math.Abs(math.Abs(phi)-half_pi) > epsln {
 		phi = math.Atan(p.oneEs * math.Tan(phi))
 	}
 	return
 }
 
+// wrapLng brings a longitude we return into range: around +lon_wrap if
+// it's set, not at all with +over, otherwise into -180..180.
+func (p *pj) wrapLng(lam float64) float64 {
+	switch {
+	case p.long_wrap_set:
+		return p.long_wrap_center + adjLng(lam-p.long_wrap_center)
+	case p.over:
+		return lam
+	}
+	return adjLng(lam)
+}
+
+// validAxis checks that +axis has each of e or w, n or s, and u or d once.
+// The vertical has to come last, since Forward and Inverse only deal in
+// two dimensions.
+func validAxis(axis string) bool {
+	return len(axis) == 3 && strings.ContainsRune("ud", rune(axis[2])) &&
+		strings.ContainsAny(axis[:2], "ew") && strings.ContainsAny(axis[:2], "ns")
+}
+
+// toAxis reorders and flips easting and northing into the +axis order.
+func (p *pj) toAxis(x, y float64) (float64, float64) {
+	if p.axis == "enu" {
+		return x, y
+	}
+	var out [2]float64
+	for i, dir := range p.axis[:2] {
+		switch dir {
+		case 'e':
+			out[i] = x
+		case 'w':
+			out[i] = -x
+		case 'n':
+			out[i] = y
+		case 's':
+			out[i] = -y
+		}
+	}
+	return out[0], out[1]
+}
+
+// fromAxis reverses toAxis.
+func (p *pj) fromAxis(a, b float64) (x, y float64) {
+	if p.axis == "enu" {
+		return a, b
+	}
+	in := [2]float64{a, b}
+	for i, dir := range p.axis[:2] {
+		switch dir {
+		case 'e':
+			x = in[i]
+		case 'w':
+			x = -in[i]
+		case 'n':
+			y = in[i]
+		case 's':
+			y = -in[i]
+		}
+	}
+	return x, y
+}
+
+func (p *pj) base() *pj {
+	return p
+}
+
+func (p *pj) ToGeocentric(lng, lat, h float64) (x, y, z float64, err error) {
+	if x, y, z, err = geodeticToGeocentric(p.aOrig, p.esOrig, lng, lat, h); err != nil {
+		return x, y, z, p.coordError(err, lng, lat)
+	}
+	return x, y, z, nil
+}
+
+func (p *pj) FromGeocentric(x, y, z float64) (lng, lat, h float64) {
+	return geocentricToGeodetic(p.aOrig, p.esOrig, x, y, z)
+}
+
 func (p *pj) ToMeter() float64 {
 	return p.to_meter
 }
@@ -298,3 +479,115 @@ func (p *pj) FromGreenwich() float64 {
 func (p *pj) Radius() float64 {
 	return p.a
 }
+
+// definedElsewhere are the keys Definition writes from the projection's
+// state rather than copying from its parameters.
+var definedElsewhere = map[string]bool{
+	"proj": true, "datum": true, "ellps": true, "a": true, "b": true, "rf": true,
+	"f": true, "es": true, "e": true, "R": true, "R_A": true, "R_V": true,
+	"R_g": true, "R_h": true, "towgs84": true, "nadgrids": true, "units": true,
+	"to_meter": true, "vunits": true, "vto_meter": true, "lat_0": true,
+	"lon_0": true, "k": true, "k_0": true, "x_0": true, "y_0": true,
+	"no_defs": true, "wktext": true, "init": true, "pm": true,
+}
+
+// angularParams are normalised to decimal degrees.
+var angularParams = map[string]bool{
+	"lat_1": true, "lat_2": true, "lat_ts": true, "lon_wrap": true,
+}
+
+func (p *pj) Definition() string {
+	def := []string{"+proj=" + p.proj}
+
+	var keys []string
+	for key := range p.params {
+		if !definedElsewhere[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		val := p.params[key]
+		if angularParams[key] {
+			val = defnNumber(parseDegreeString(val))
+		} else if f, err := strconv.ParseFloat(val, 64); err == nil {
+			val = defnNumber(f)
+		}
+		if key == "axis" && val == "enu" {
+			continue
+		}
+		if val == "" {
+			def = append(def, "+"+key)
+		} else {
+			def = append(def, "+"+key+"="+val)
+		}
+	}
+
+	lnglat := isLngLat(p.proj)
+	switch {
+	case lnglat || p.proj == "geocent":
+		if p.phi0 != 0 {
+			def = append(def, "+lat_0="+defnNumber(p.phi0/d2r))
+		}
+		if p.lam0 != 0 {
+			def = append(def, "+lon_0="+defnNumber(p.lam0/d2r))
+		}
+	case p.proj != "utm":
+		def = append(def,
+			"+lat_0="+defnNumber(p.phi0/d2r),
+			"+lon_0="+defnNumber(p.lam0/d2r),
+			"+k_0="+defnNumber(p.k0),
+			"+x_0="+defnNumber(p.x0),
+			"+y_0="+defnNumber(p.y0))
+	}
+
+	if p.from_greenwich != 0 {
+		def = append(def, "+pm="+defnNumber(p.from_greenwich/d2r))
+	}
+	// the ellipsoid as given, since some projections work on a sphere
+	if p.esOrig == 0 {
+		def = append(def, "+R="+defnNumber(p.aOrig))
+	} else {
+		def = append(def, "+a="+defnNumber(p.aOrig), "+rf="+defnNumber(1/(1-math.Sqrt(1-p.esOrig))))
+	}
+	switch p.datumType {
+	case PJD_3PARAM, PJD_7PARAM, PJD_WGS84:
+		n := 3
+		if p.datumType == PJD_7PARAM {
+			n = 7
+		}
+		params := strings.Split(p.params["towgs84"], ",")
+		for i := range params[:n] {
+			f, _ := strconv.ParseFloat(strings.TrimSpace(params[i]), 64)
+			params[i] = defnNumber(f)
+		}
+		def = append(def, "+towgs84="+strings.Join(params[:n], ","))
+	case PJD_GRIDSHIFT:
+		if p.nadgrids != "" {
+			def = append(def, "+nadgrids="+p.nadgrids)
+		}
+	}
+
+	if !lnglat {
+		def = append(def, defnUnits("units", "to_meter", p.to_meter))
+	}
+	if p.vto_meter != p.to_meter {
+		def = append(def, defnUnits("vunits", "vto_meter", p.vto_meter))
+	}
+	return strings.Join(def, " ")
+}
+
+// defnUnits names a unit from units_list if it can.
+func defnUnits(key, toMeterKey string, toMeter float64) string {
+	for name, u := range units_list {
+		if u.to_meter == toMeter {
+			return "+" + key + "=" + name
+		}
+	}
+	return "+" + toMeterKey + "=" + defnNumber(toMeter)
+}
+
+// defnNumber rounds away the noise from converting units.
+func defnNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', 12, 64)
+}
@@ -5,6 +5,7 @@
 package projectron
 
 import (
+	"errors"
 	"math"
 	"testing"
 	// "fmt"
@@ -14,6 +15,19 @@ func TestDegreeString(t *testing.T) {
 	for _, pm := range pm_list {
 		parseDegreeString(pm.defn)
 	}
+	for _, test := range []struct {
+		ds  string
+		exp float64
+	}{
+		{"2d20'14.025\"E", 2 + 20.0/60 + 14.025/3600},
+		{"17d40'W", -(17 + 40.0/60)},
+		{"74d04'51.3\"W", -(74 + 4.0/60 + 51.3/3600)},
+		{"-3.5", -3.5},
+	} {
+		if f := parseDegreeString(test.ds); !within(test.exp, f, 1e-12) {
+			t.Errorf("%s: expected %v, got %v", test.ds, test.exp, f)
+		}
+	}
 }
 
 func TestProjString(t *testing.T) {
@@ -70,41 +84,859 @@ func TestMercator(t *testing.T) {
 }
 
 func TestLCC(t *testing.T) {
-	t.Skip("not implemented")
-	pj, err := NewProjection("+proj=lcc +lat_0=18 +lat_1=18 +lon_0=-77 +k_0=1 +k_0=1.0 +R=6378137")
+	checkRoundTrip(t, []roundTrip{
+		// EPSG guidance note 7-2: Jamaica 1969 / Jamaica National Grid (1SP)
+		{"1SP", "+proj=lcc +lat_0=18 +lat_1=18 +lon_0=-77 +k_0=1 +x_0=250000 +y_0=150000 +ellps=clrk66",
+			-(76 + 56.0/60 + 37.26/3600), 17 + 55.0/60 + 55.8/3600, 255966.58, 142493.51, 1.0e-2},
+		// EPSG guidance note 7-2: NAD27 / Texas South Central (2SP), in metres
+		{"2SP", "+proj=lcc +lat_1=28.383333333 +lat_2=30.283333333 +lat_0=27.833333333 +lon_0=-99 +x_0=609601.2192024384 +y_0=0 +ellps=clrk66",
+			-96, 28.5, 903277.80, 77650.94, 1.0e-2},
+		// PROJ builtins.gie
+		{"GRS80", "+proj=lcc +ellps=GRS80 +lat_1=0.5 +lat_2=2",
+			2, 1, 222588.439735968, 110660.533870800, 1.0e-4},
+		{"GRS80 south", "+proj=lcc +ellps=GRS80 +lat_1=0.5 +lat_2=2",
+			-2, -1, -222756.879700279, -110532.797660827, 1.0e-4},
+	})
+}
+
+func TestLCCApex(t *testing.T) {
+	pj, err := NewProjection("+proj=lcc +ellps=GRS80 +lat_1=33 +lat_2=45 +lat_0=39 +lon_0=-96")
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-
-	// c, n, rho0 float64
-	// phi2, phi1 float64
-	// ellips bool
-	// lcc, _ := pj.(*LCC)
-
-	lng0, lat0 := -.1396263, .4712389
-	// println(lng0, lat0)
-	expx, expy := 8701763.068335464, -139008.773062367
-	x, y, err := pj.Forward(lng0, lat0)
+	if _, _, err := pj.Forward(0, -half_pi); !errors.Is(err, ErrToleranceCondition) {
+		t.Errorf("expected tolerance condition at the far pole, got %v", err)
+	}
+	// the apex of the cone is the north pole and comes back as it
+	x, y, err := pj.Forward(-96*d2r, half_pi)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	_, lat, err := pj.Inverse(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !close(lat, half_pi) {
+		t.Errorf("apex should invert to the pole, got %f", lat)
 	}
 
-	// should translate forward
-	if !close(expx, x) || !close(expy, y) {
-		t.Errorf("fwd translation off: (%f, %f) - (%f, %f)", expx, expy, x, y)
+	// and the same when the projection is centred there
+	for _, defn := range []string{
+		"+proj=lcc +ellps=GRS80 +lat_1=60 +lat_2=70 +lat_0=90 +lon_0=-96",
+		"+proj=lcc +R=6400000 +lat_1=-60 +lat_2=-70 +lat_0=-90 +lon_0=-96",
+	} {
+		pj, err := NewProjection(defn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, lat, err := pj.Inverse(0, 0)
+		if err != nil {
+			t.Errorf("%s: %v", defn, err)
+		}
+		if !close(math.Abs(lat), half_pi) {
+			t.Errorf("%s: apex should invert to the pole, got %f", defn, lat)
+		}
 	}
+}
+
+// roundTrip is a point that should project to expx, expy within tol metres
+// and back to within well under a millimetre.
+type roundTrip struct {
+	name, defn string
+	lng0, lat0 float64
+	expx, expy float64
+	tol        float64
+}
+
+func checkRoundTrip(t *testing.T, cases []roundTrip) {
+	t.Helper()
+	for _, tt := range cases {
+		pj, err := NewProjection(tt.defn)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		lng0, lat0 := tt.lng0*d2r, tt.lat0*d2r
+		x, y, err := pj.Forward(lng0, lat0)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+
+		// should translate forward
+		if !within(tt.expx, x, tt.tol) || !within(tt.expy, y, tt.tol) {
+			t.Errorf("%s: fwd translation off: (%f, %f) - (%f, %f)", tt.name, tt.expx, tt.expy, x, y)
+		}
 
-	// lng1, lat1, err := pj.Inverse(x, y)
-	// if err != nil {
-	// 	t.Error(err)
-	// }
-	// // should translate back
-	// if !close(lng0, lng1) || !close(lat0, lat1) {
-	// 	t.Errorf("inv translation off: (%f, %f) - (%f, %f)", lng0, lat0, lng1, lat1)
-	// }
+		lng1, lat1, err := pj.Inverse(x, y)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		// should translate back
+		if !within(lng0, lng1, 1.0e-11) || !within(lat0, lat1, 1.0e-11) {
+			t.Errorf("%s: inv translation off: (%.12f, %.12f) - (%.12f, %.12f)", tt.name, lng0, lat0, lng1, lat1)
+		}
+	}
 }
 
+// definition is the Definition of a projection we made.
+func definition(p Projection) string {
+	return p.(Definer).Definition()
+}
 
 func close(a, b float64) bool {
 	return math.Abs(a-b) < 1.0e-5
 }
+
+func within(a, b, tol float64) bool {
+	return math.Abs(a-b) < tol
+}
+
+func TestTransverseMercator(t *testing.T) {
+	checkRoundTrip(t, []roundTrip{
+		// EPSG guidance note 7-2: OSGB 1936 / British National Grid
+		{"BNG", "+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy",
+			0.5, 50.5, 577274.98, 69740.49, 1.0e-2},
+		{"BNG approx", "+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy +approx",
+			0.5, 50.5, 577274.98, 69740.49, 1.0e-2},
+		// PROJ builtins.gie
+		{"GRS80", "+proj=tmerc +ellps=GRS80",
+			2, 1, 222650.796797586, 110642.229411933, 1.0e-4},
+		{"GRS80 approx", "+proj=tmerc +ellps=GRS80 +approx",
+			2, 1, 222650.796795778, 110642.229411927, 1.0e-4},
+		{"sphere", "+proj=tmerc +R=6400000",
+			2, 1, 223413.466406322, 111769.145040597, 1.0e-4},
+		// far from the central meridian only the Krüger series holds up
+		{"GRS80 wide", "+proj=tmerc +ellps=GRS80",
+			30, 60, 1633178.735906, 7037439.986868, 1.0e-4},
+	})
+}
+
+func TestUTM(t *testing.T) {
+	tests := []struct {
+		utm, tmerc string
+	}{
+		{"+proj=utm +zone=33 +ellps=WGS84",
+			"+proj=tmerc +lon_0=15 +k=0.9996 +x_0=500000 +ellps=WGS84"},
+		{"+proj=utm +zone=19 +south +ellps=GRS80",
+			"+proj=tmerc +lon_0=-69 +k=0.9996 +x_0=500000 +y_0=10000000 +ellps=GRS80"},
+		{"+proj=utm +lon_0=-69 +ellps=GRS80",
+			"+proj=tmerc +lon_0=-69 +k=0.9996 +x_0=500000 +ellps=GRS80"},
+	}
+	lng0, lat0 := -70.5*d2r, -33.4*d2r
+	for _, tt := range tests {
+		utm, err := NewProjection(tt.utm)
+		if err != nil {
+			t.Errorf("%s: %v", tt.utm, err)
+			continue
+		}
+		tm, err := NewProjection(tt.tmerc)
+		if err != nil {
+			t.Errorf("%s: %v", tt.tmerc, err)
+			continue
+		}
+		x0, y0, _ := utm.Forward(lng0, lat0)
+		x1, y1, _ := tm.Forward(lng0, lat0)
+		if !within(x0, x1, 1.0e-6) || !within(y0, y1, 1.0e-6) {
+			t.Errorf("%s: expected (%f, %f), got (%f, %f)", tt.utm, x1, y1, x0, y0)
+		}
+	}
+
+	for _, defn := range []string{
+		"+proj=utm +zone=0 +ellps=WGS84",
+		"+proj=utm +zone=61 +ellps=WGS84",
+		"+proj=utm +zone=north +ellps=WGS84",
+		"+proj=utm +zone=33 +R=6371000",
+	} {
+		if _, err := NewProjection(defn); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s: expected ErrInvalidParam, got %v", defn, err)
+		}
+	}
+}
+
+func TestUTMZone(t *testing.T) {
+	tests := []struct {
+		lng, lat float64
+		zone     int
+		south    bool
+	}{
+		{-180, 0, 1, false},
+		{179.9, 10, 60, false},
+		{180, 10, 1, false},
+		{-0.5, 51.5, 30, false},
+		{151.2, -33.9, 56, true},
+		// southwestern Norway
+		{5.3, 60.4, 32, false},
+		{2.9, 60.4, 31, false},
+		// Svalbard
+		{8, 78, 31, false},
+		{10, 78, 33, false},
+		{25, 78, 35, false},
+		{40, 78, 37, false},
+		{40, 85, 37, false},
+	}
+	for _, tt := range tests {
+		zone, south := UTMZone(tt.lng*d2r, tt.lat*d2r)
+		if zone != tt.zone || south != tt.south {
+			t.Errorf("(%f, %f): expected %d %t, got %d %t", tt.lng, tt.lat, tt.zone, tt.south, zone, south)
+		}
+	}
+}
+
+func TestStereographic(t *testing.T) {
+	tests := []struct {
+		name, defn string
+		lng0, lat0 float64
+		expx, expy float64
+		tol        float64
+	}{
+		// EPSG guidance note 7-2: WGS 84 / UPS North (variant A)
+		{"polar A", "+proj=stere +lat_0=90 +lon_0=0 +k=0.994 +x_0=2000000 +y_0=2000000 +ellps=WGS84",
+			44, 73, 3320416.75, 632668.43, 1.0e-2},
+		// EPSG guidance note 7-2: WGS 84 / Australian Antarctic Polar Stereographic (variant B)
+		{"polar B", "+proj=stere +lat_0=-90 +lat_ts=-71 +lon_0=70 +x_0=6000000 +y_0=6000000 +ellps=WGS84",
+			120, -75, 7255380.79, 7053389.56, 1.0e-2},
+		// EPSG guidance note 7-2: Amersfoort / RD New
+		{"sterea", "+proj=sterea +lat_0=52.15616055555555 +lon_0=5.38763888888889 +k=0.9999079 +x_0=155000 +y_0=463000 +ellps=bessel",
+			6, 53, 196105.283, 557057.739, 1.0e-3},
+		// PROJ builtins.gie
+		{"somerc", "+proj=somerc +ellps=GRS80",
+			2, 1, 222638.981586547, 110579.965218249, 1.0e-6},
+		{"somerc south", "+proj=somerc +ellps=GRS80",
+			-2, -1, -222638.981586547, -110579.965218249, 1.0e-6},
+		// the origin of CH1903+ / LV95
+		{"somerc LV95", "+proj=somerc +lat_0=46.95240555555556 +lon_0=7.439583333333333 +k_0=1 +x_0=2600000 +y_0=1200000 +ellps=bessel",
+			7.439583333333333, 46.95240555555556, 2600000, 1200000, 1.0e-6},
+	}
+	for _, tt := range tests {
+		pj, err := NewProjection(tt.defn)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		lng0, lat0 := tt.lng0*d2r, tt.lat0*d2r
+		x, y, err := pj.Forward(lng0, lat0)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+
+		// should translate forward
+		if !within(tt.expx, x, tt.tol) || !within(tt.expy, y, tt.tol) {
+			t.Errorf("%s: fwd translation off: (%f, %f) - (%f, %f)", tt.name, tt.expx, tt.expy, x, y)
+		}
+
+		lng1, lat1, err := pj.Inverse(x, y)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		// should translate back
+		if !within(lng0, lng1, 1.0e-11) || !within(lat0, lat1, 1.0e-11) {
+			t.Errorf("%s: inv translation off: (%f, %f) - (%f, %f)", tt.name, lng0, lat0, lng1, lat1)
+		}
+	}
+}
+
+func TestStereographicAspects(t *testing.T) {
+	for _, defn := range []string{
+		"+proj=stere +lat_0=90 +lat_ts=70 +lon_0=-45 +ellps=WGS84",
+		"+proj=stere +lat_0=-90 +ellps=WGS84",
+		"+proj=stere +lat_0=30 +lon_0=10 +ellps=GRS80",
+		"+proj=stere +lat_0=0 +lon_0=10 +ellps=GRS80",
+		"+proj=stere +lat_0=90 +lat_ts=70 +R=6400000",
+		"+proj=stere +lat_0=-90 +R=6400000",
+		"+proj=stere +lat_0=30 +lon_0=10 +R=6400000",
+		"+proj=stere +lat_0=0 +lon_0=10 +R=6400000",
+		"+proj=sterea +lat_0=-40 +lon_0=20 +ellps=GRS80",
+	} {
+		pj, err := NewProjection(defn)
+		if err != nil {
+			t.Errorf("%s: %v", defn, err)
+			continue
+		}
+		for _, ll := range [][2]float64{{20, 40}, {-30, 80}, {15, -60}} {
+			lng0, lat0 := ll[0]*d2r, ll[1]*d2r
+			x, y, err := pj.Forward(lng0, lat0)
+			if err != nil {
+				t.Errorf("%s: %v", defn, err)
+				continue
+			}
+			lng1, lat1, err := pj.Inverse(x, y)
+			if err != nil {
+				t.Errorf("%s: %v", defn, err)
+			}
+			if !within(lng0, lng1, 1.0e-11) || !within(lat0, lat1, 1.0e-11) {
+				t.Errorf("%s: inv translation off: (%f, %f) - (%f, %f)", defn, lng0, lat0, lng1, lat1)
+			}
+		}
+	}
+
+	pj, _ := NewProjection("+proj=stere +lat_0=90 +R=6400000")
+	if _, _, err := pj.Forward(0, -half_pi); !errors.Is(err, ErrToleranceCondition) {
+		t.Errorf("expected tolerance condition at the opposite pole, got %v", err)
+	}
+}
+
+func TestAlbersEqualArea(t *testing.T) {
+	checkRoundTrip(t, []roundTrip{
+		// PROJ builtins.gie
+		{"GRS80", "+proj=aea +ellps=GRS80 +lat_1=0 +lat_2=2",
+			2, 1, 222571.608757106, 110653.326743030, 1.0e-4},
+		{"GRS80 south", "+proj=aea +ellps=GRS80 +lat_1=0 +lat_2=2",
+			2, -1, 222706.306508391, -110484.267144400, 1.0e-4},
+		{"sphere", "+proj=aea +R=6400000 +lat_1=0 +lat_2=2",
+			2, 1, 223334.085170885, 111780.431884472, 1.0e-4},
+		// EPSG:5070, NAD83 / Conus Albers, at its false origin
+		{"conus", "+proj=aea +lat_0=23 +lon_0=-96 +lat_1=29.5 +lat_2=45.5 +x_0=0 +y_0=0 +datum=NAD83 +units=m",
+			-96, 23, 0, 0, 1.0e-4},
+	})
+
+	if _, err := NewProjection("+proj=aea +ellps=GRS80 +lat_1=30 +lat_2=-30"); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam for opposite parallels, got %v", err)
+	}
+}
+
+func TestLambertAzimuthalEqualArea(t *testing.T) {
+	checkRoundTrip(t, []roundTrip{
+		// PROJ builtins.gie
+		{"GRS80", "+proj=laea +ellps=GRS80",
+			2, 1, 222602.471450095, 110589.827224410, 1.0e-4},
+		{"sphere", "+proj=laea +R=6400000",
+			2, 1, 223365.281370125, 111716.668072916, 1.0e-4},
+		// EPSG guidance note 7-2: ETRS89 / LAEA Europe
+		{"EPSG:3035", "+proj=laea +lat_0=52 +lon_0=10 +x_0=4321000 +y_0=3210000 +ellps=GRS80",
+			5, 50, 3962799.45, 2999718.85, 1.0e-2},
+	})
+
+	// the whole globe fits in a disc about 12750km across
+	for _, defn := range []string{
+		"+proj=laea +ellps=GRS80",
+		"+proj=laea +lat_0=52 +lon_0=10 +ellps=GRS80",
+		"+proj=laea +lat_0=90 +ellps=GRS80",
+		"+proj=laea +lat_0=-90 +ellps=GRS80",
+		"+proj=laea +R=6400000",
+	} {
+		pj, _ := NewProjection(defn)
+		if _, _, err := pj.Inverse(13000000, 1000); !errors.Is(err, ErrToleranceCondition) {
+			t.Errorf("%s: expected a tolerance condition outside the disc, got %v", defn, err)
+		}
+	}
+}
+
+func TestLambertAzimuthalEqualAreaAspects(t *testing.T) {
+	for _, defn := range []string{
+		"+proj=laea +lat_0=90 +ellps=WGS84",
+		"+proj=laea +lat_0=-90 +ellps=WGS84",
+		"+proj=laea +lat_0=0 +lon_0=10 +ellps=WGS84",
+		"+proj=laea +lat_0=45 +lon_0=-100 +ellps=WGS84",
+		"+proj=laea +lat_0=90 +R=6400000",
+		"+proj=laea +lat_0=-90 +R=6400000",
+		"+proj=laea +lat_0=0 +lon_0=10 +R=6400000",
+		"+proj=laea +lat_0=45 +lon_0=-100 +R=6400000",
+	} {
+		pj, err := NewProjection(defn)
+		if err != nil {
+			t.Errorf("%s: %v", defn, err)
+			continue
+		}
+		for _, ll := range [][2]float64{{20, 40}, {-30, 80}, {15, -60}} {
+			lng0, lat0 := ll[0]*d2r, ll[1]*d2r
+			x, y, err := pj.Forward(lng0, lat0)
+			if err != nil {
+				t.Errorf("%s: %v", defn, err)
+				continue
+			}
+			lng1, lat1, err := pj.Inverse(x, y)
+			if err != nil {
+				t.Errorf("%s: %v", defn, err)
+			}
+			if !within(lng0, lng1, 1.0e-11) || !within(lat0, lat1, 1.0e-11) {
+				t.Errorf("%s: inv translation off: (%f, %f) - (%f, %f)", defn, lng0, lat0, lng1, lat1)
+			}
+		}
+	}
+}
+
+func TestAzimuthals(t *testing.T) {
+	checkRoundTrip(t, []roundTrip{
+		// PROJ builtins.gie
+		{"aeqd", "+proj=aeqd +ellps=GRS80",
+			2, 1, 222616.522190, 110596.996550, 1.0e-3},
+		{"aeqd sphere", "+proj=aeqd +R=6400000",
+			2, 1, 223379.456047271, 111723.757570854, 1.0e-4},
+		{"ortho sphere", "+proj=ortho +R=6400000",
+			2, 1, 223322.760576727, 111695.401198614, 1.0e-4},
+		{"gnom", "+proj=gnom +R=6400000",
+			2, 1, 223492.924747185, 111780.509206593, 1.0e-4},
+		// EPSG guidance note 7-2: Guam 1963 / Guam SPCS
+		{"aeqd guam", "+proj=aeqd +lat_0=13.47246635277778 +lon_0=144.7487507055556 +x_0=50000 +y_0=50000 +guam +ellps=clrk66",
+			144 + 38.0/60 + 7.19265/3600, 13 + 20.0/60 + 20.53846/3600, 37712.48, 35242.00, 1.0e-2},
+	})
+}
+
+func TestAzimuthalAspects(t *testing.T) {
+	for _, defn := range []string{
+		"+proj=aeqd +lat_0=90 +ellps=WGS84",
+		"+proj=aeqd +lat_0=-90 +ellps=WGS84",
+		"+proj=aeqd +lat_0=40 +lon_0=-100 +ellps=WGS84",
+		"+proj=aeqd +lat_0=40 +lon_0=-100 +R=6400000",
+		"+proj=ortho +lat_0=90 +ellps=WGS84",
+		"+proj=ortho +lat_0=30 +lon_0=10 +ellps=WGS84",
+		"+proj=ortho +lat_0=0 +lon_0=10 +ellps=WGS84",
+		"+proj=ortho +lat_0=30 +lon_0=10 +R=6400000",
+		"+proj=gnom +lat_0=90 +R=6400000",
+		"+proj=gnom +lat_0=30 +lon_0=10 +R=6400000",
+	} {
+		pj, err := NewProjection(defn)
+		if err != nil {
+			t.Errorf("%s: %v", defn, err)
+			continue
+		}
+		for _, ll := range [][2]float64{{20, 40}, {-10, 80}, {15, 60}} {
+			lng0, lat0 := ll[0]*d2r, ll[1]*d2r
+			x, y, err := pj.Forward(lng0, lat0)
+			if err != nil {
+				t.Errorf("%s: %v", defn, err)
+				continue
+			}
+			lng1, lat1, err := pj.Inverse(x, y)
+			if err != nil {
+				t.Errorf("%s: %v", defn, err)
+			}
+			if !within(lng0, lng1, 1.0e-11) || !within(lat0, lat1, 1.0e-11) {
+				t.Errorf("%s: inv translation off: (%f, %f) - (%f, %f)", defn, lng0, lat0, lng1, lat1)
+			}
+		}
+	}
+}
+
+func TestAzimuthalEquidistantAntipode(t *testing.T) {
+	pj, err := NewProjection("+proj=aeqd +lat_0=40 +lon_0=-100 +ellps=WGS84")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the antipode of the centre and points just beside it, where the
+	// geodesics are longest and hardest to find
+	for _, ll := range [][2]float64{{80, -40}, {80.3, -40}, {80, -39.7}, {79.9, -40.1}} {
+		lng0, lat0 := ll[0]*d2r, ll[1]*d2r
+		x, y, err := pj.Forward(lng0, lat0)
+		if err != nil {
+			t.Errorf("%v: %v", ll, err)
+			continue
+		}
+		if rho := math.Hypot(x, y); rho < 19900000 || rho > 20100000 {
+			t.Errorf("%v: %f from the centre", ll, rho)
+		}
+		lng1, lat1, err := pj.Inverse(x, y)
+		if err != nil {
+			t.Errorf("%v: %v", ll, err)
+		}
+		if !within(lng0, lng1, 1.0e-9) || !within(lat0, lat1, 1.0e-9) {
+			t.Errorf("%v: inv translation off: (%f, %f) - (%f, %f)", ll, lng0, lat0, lng1, lat1)
+		}
+	}
+}
+
+func TestAzimuthalVisibility(t *testing.T) {
+	for _, defn := range []string{
+		"+proj=ortho +lat_0=40 +R=6400000",
+		"+proj=ortho +lat_0=40 +ellps=WGS84",
+		"+proj=ortho +lat_0=90 +R=6400000",
+		"+proj=gnom +lat_0=40 +R=6400000",
+		"+proj=gnom +lat_0=0 +R=6400000",
+	} {
+		pj, err := NewProjection(defn)
+		if err != nil {
+			t.Errorf("%s: %v", defn, err)
+			continue
+		}
+		if _, _, err := pj.Forward(100*d2r, -10*d2r); !errors.Is(err, ErrPointNotVisible) {
+			t.Errorf("%s: expected ErrPointNotVisible, got %v", defn, err)
+		}
+	}
+
+	// the ellipsoid's disc is narrower than the sphere's near the poles,
+	// and the inverse has nothing to converge on between the two
+	pj, err := NewProjection("+proj=ortho +lat_0=0 +ellps=WGS84")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, xy := range [][2]float64{{0, 6362000}, {100000, 6360000}} {
+		if _, _, err := pj.Inverse(xy[0], xy[1]); !errors.Is(err, ErrPointNotVisible) {
+			t.Errorf("%v: expected ErrPointNotVisible, got %v", xy, err)
+		}
+	}
+	if _, _, err := pj.Inverse(-446469.59, 6362191.6575); !errors.Is(err, ErrNoConvergence) {
+		t.Errorf("expected ErrNoConvergence, got %v", err)
+	}
+}
+
+func TestEquirectangular(t *testing.T) {
+	checkRoundTrip(t, []roundTrip{
+		// EPSG:4087, WGS 84 / World Equidistant Cylindrical
+		{"EPSG:4087", "+proj=eqc +lat_ts=0 +lat_0=0 +lon_0=0 +x_0=0 +y_0=0 +datum=WGS84 +units=m",
+			10, 55, 1113194.907933, 6122571.993630, 1.0e-4},
+		{"EPSG:4087", "+proj=eqc +lat_ts=0 +lat_0=0 +lon_0=0 +x_0=0 +y_0=0 +datum=WGS84 +units=m",
+			-179, -89, -19926188.851996, -9907434.680601, 1.0e-4},
+		// EPSG:32662, WGS 84 / Plate Carree
+		{"EPSG:32662", "+proj=eqc +lat_ts=0 +lat_0=0 +lon_0=0 +x_0=0 +y_0=0 +ellps=WGS84 +datum=WGS84 +units=m",
+			-74, 40.7, -8237642.318702, 4530703.275286, 1.0e-4},
+		// PROJ builtins.gie
+		{"sphere", "+proj=eqc +R=6400000",
+			2, 1, 223402.144255274, 111701.072127637, 1.0e-4},
+		{"lat_ts", "+proj=eqc +lat_ts=30 +lat_0=10 +lon_0=5 +x_0=1000 +y_0=2000 +R=6400000",
+			7, 12, 194471.932185, 225402.144255, 1.0e-4},
+		{"lat_1", "+proj=eqc +lat_1=30 +lat_0=10 +lon_0=5 +x_0=1000 +y_0=2000 +R=6400000",
+			7, 12, 194471.932185, 225402.144255, 1.0e-4},
+	})
+
+	if _, err := NewProjection("+proj=eqc +lat_ts=90 +R=6400000"); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam for lat_ts=90, got %v", err)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	var tests = []struct {
+		defns []string
+		exp   string
+	}{
+		{
+			[]string{
+				"+proj=utm +zone=33 +datum=WGS84",
+				"+proj=utm +zone=33 +ellps=WGS84 +towgs84=0,0,0 +units=m +no_defs",
+				"+units=m +towgs84=0.0,0.0,0.0 +a=6378137 +rf=298.257223563 +zone=33 +proj=utm",
+			},
+			"+proj=utm +zone=33 +a=6378137 +rf=298.257223563 +towgs84=0,0,0 +units=m",
+		},
+		{
+			[]string{
+				"+proj=lcc +lat_1=33 +lat_2=45 +lat_0=23 +lon_0=-96 +datum=NAD83",
+				"+proj=lcc +lat_1=33d +lat_2=45d0'0\" +lat_0=23 +lon_0=-96 +x_0=0 +y_0=0 +ellps=GRS80 +towgs84=0,0,0",
+			},
+			"+proj=lcc +lat_1=33 +lat_2=45 +lat_0=23 +lon_0=-96 +k_0=1 +x_0=0 +y_0=0 +a=6378137 +rf=298.257222101 +towgs84=0,0,0 +units=m",
+		},
+		{
+			[]string{
+				"+proj=merc +a=6378137 +b=6378137 +nadgrids=@null",
+				"+proj=merc +R=6378137 +k=1 +nadgrids=@null",
+			},
+			"+proj=merc +lat_0=0 +lon_0=0 +k_0=1 +x_0=0 +y_0=0 +R=6378137 +nadgrids=@null +units=m",
+		},
+		{
+			[]string{
+				"+proj=longlat +datum=WGS84",
+				"+proj=longlat +ellps=WGS84 +towgs84=0,0,0 +no_defs",
+			},
+			"+proj=longlat +a=6378137 +rf=298.257223563 +towgs84=0,0,0",
+		},
+		// these project on a sphere, but the datum is still an ellipsoid
+		{
+			[]string{"+proj=eqc +lat_ts=30 +ellps=GRS80"},
+			"+proj=eqc +lat_ts=30 +lat_0=0 +lon_0=0 +k_0=1 +x_0=0 +y_0=0 +a=6378137 +rf=298.257222101 +units=m",
+		},
+		{
+			[]string{"+proj=gnom +lat_0=40 +lon_0=-100 +ellps=GRS80"},
+			"+proj=gnom +lat_0=40 +lon_0=-100 +k_0=1 +x_0=0 +y_0=0 +a=6378137 +rf=298.257222101 +units=m",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, defn := range tt.defns {
+			pj, err := NewProjection(defn)
+			if err != nil {
+				t.Errorf("%s: %v", defn, err)
+				continue
+			}
+			got := definition(pj)
+			if got != tt.exp {
+				t.Errorf("%s:\n got %s\nwant %s", defn, got, tt.exp)
+			}
+
+			// the definition should describe the same projection
+			again, err := NewProjection(got)
+			if err != nil {
+				t.Errorf("%s: %v", got, err)
+				continue
+			}
+			if definition(again) != got {
+				t.Errorf("%s: not stable, got %s", got, definition(again))
+			}
+			p, q := pj.(impl).base(), again.(impl).base()
+			if p.aOrig != q.aOrig || !within(p.esOrig, q.esOrig, 1.0e-15) {
+				t.Errorf("%s: the ellipsoid changed from %v, %v to %v, %v", got, p.aOrig, p.esOrig, q.aOrig, q.esOrig)
+			}
+		}
+	}
+}
+
+func TestUnits(t *testing.T) {
+	const usFt = 1200.0 / 3937
+	const caZone3 = "+proj=lcc +lat_1=38.43333333333333 +lat_2=37.06666666666667 +lat_0=36.5 +lon_0=-120.5 +x_0=2000000.0001016 +y_0=500000.0001016001 +datum=NAD83"
+	metres, err := NewProjection(caZone3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lng, lat := -122.25*d2r, 37.75*d2r
+	mx, my, _ := metres.Forward(lng, lat)
+
+	for _, defn := range []string{"+units=us-ft", "+to_meter=0.304800609601219", "+to_meter=1200/3937"} {
+		feet, err := NewProjection(caZone3 + " " + defn)
+		if err != nil {
+			t.Errorf("%s: %v", defn, err)
+			continue
+		}
+		if !within(usFt, feet.ToMeter(), 1e-15) {
+			t.Errorf("%s: expected to_meter of %v, got %v", defn, usFt, feet.ToMeter())
+		}
+		x, y, _ := feet.Forward(lng, lat)
+		if !within(mx/usFt, x, 1e-5) || !within(my/usFt, y, 1e-5) {
+			t.Errorf("%s: expected (%f, %f), got (%f, %f)", defn, mx/usFt, my/usFt, x, y)
+		}
+		if lng1, lat1, _ := feet.Inverse(x, y); !within(lng, lng1, 1e-11) || !within(lat, lat1, 1e-11) {
+			t.Errorf("%s: inverse off: (%f, %f) - (%f, %f)", defn, lng, lat, lng1, lat1)
+		}
+	}
+
+	if pj, _ := NewProjection("+proj=merc +ellps=WGS84 +to_meter=1/3.28"); pj == nil || !within(1/3.28, pj.ToMeter(), 1e-15) {
+		t.Errorf("expected to_meter of 1/3.28")
+	}
+
+	// vertical units are separate from the horizontal ones
+	pj, err := NewProjection("+proj=utm +zone=10 +datum=NAD83 +vunits=us-ft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pj.(impl).base(); p.to_meter != 1 || !within(usFt, p.vto_meter, 1e-15) {
+		t.Errorf("expected to_meter 1 and vto_meter %v, got %v and %v", usFt, p.to_meter, p.vto_meter)
+	}
+	pj, _ = NewProjection("+proj=utm +zone=10 +datum=NAD83 +units=ft")
+	if p := pj.(impl).base(); p.vto_meter != 0.3048 {
+		t.Errorf("expected vto_meter to follow to_meter, got %v", p.vto_meter)
+	}
+
+	for _, test := range []struct {
+		defn string
+		err  error
+	}{
+		{"+proj=merc +ellps=WGS84 +units=furlong", ErrUnknownUnit},
+		{"+proj=merc +ellps=WGS84 +vunits=cubit", ErrUnknownUnit},
+		{"+proj=merc +ellps=WGS84 +to_meter=0", ErrInvalidParam},
+		{"+proj=merc +ellps=WGS84 +to_meter=-1", ErrInvalidParam},
+		{"+proj=merc +ellps=WGS84 +to_meter=1/0", ErrInvalidParam},
+		{"+proj=merc +ellps=WGS84 +vto_meter=feet", ErrInvalidParam},
+	} {
+		if _, err := NewProjection(test.defn); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.defn, test.err, err)
+		}
+	}
+}
+
+func TestAxis(t *testing.T) {
+	for _, axis := range []string{"enu", "neu", "wsu", "nwd", "esd"} {
+		if _, err := NewProjection("+proj=merc +ellps=WGS84 +axis=" + axis); err != nil {
+			t.Errorf("%s: %v", axis, err)
+		}
+	}
+	for _, axis := range []string{"", "en", "enuu", "een", "nnu", "ene", "uen", "enx"} {
+		if _, err := NewProjection("+proj=merc +ellps=WGS84 +axis=" + axis); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%q: expected ErrInvalidParam, got %v", axis, err)
+		}
+	}
+
+	lng, lat := 16.75*d2r, 52.5*d2r
+	enu, _ := NewProjection("+proj=utm +zone=33 +datum=WGS84")
+	e, n, _ := enu.Forward(lng, lat)
+	for _, test := range []struct {
+		axis string
+		x, y float64
+	}{
+		{"neu", n, e},
+		{"wsu", -e, -n},
+		{"nwu", n, -e},
+		{"sed", -n, e},
+	} {
+		pj, err := NewProjection("+proj=utm +zone=33 +datum=WGS84 +axis=" + test.axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y, _ := pj.Forward(lng, lat)
+		if x != test.x || y != test.y {
+			t.Errorf("%s: expected (%f, %f), got (%f, %f)", test.axis, test.x, test.y, x, y)
+		}
+		lng1, lat1, _ := pj.Inverse(x, y)
+		if !within(lng, lng1, 1e-11) || !within(lat, lat1, 1e-11) {
+			t.Errorf("%s: inverse off: (%f, %f) - (%f, %f)", test.axis, lng, lat, lng1, lat1)
+		}
+	}
+
+	// EPSG:4326 is latitude first
+	latlng, _ := NewProjection("+proj=longlat +datum=WGS84 +axis=neu")
+	if x, y, _ := latlng.Forward(lng, lat); !within(lat, x, 1e-15) || !within(lng, y, 1e-15) {
+		t.Errorf("expected (%f, %f), got (%f, %f)", lat, lng, x, y)
+	}
+	if lng1, lat1, _ := latlng.Inverse(lat, lng); !within(lng, lng1, 1e-15) || !within(lat, lat1, 1e-15) {
+		t.Errorf("expected (%f, %f), got (%f, %f)", lng, lat, lng1, lat1)
+	}
+}
+
+func TestPrimeMeridian(t *testing.T) {
+	paris := 2 + 20.0/60 + 14.025/3600
+	ferro := -(17 + 40.0/60)
+	for _, test := range []struct {
+		name, defn, greenwich string
+	}{
+		{
+			"NTF (Paris) / Lambert zone II",
+			"+proj=lcc +lat_1=46.8 +lat_0=46.8 +lon_0=0 +k_0=0.99987742 +x_0=600000 +y_0=2200000 +a=6378249.2 +b=6356515 +pm=paris",
+			"+proj=lcc +lat_1=46.8 +lat_0=46.8 +lon_0=2.337229166666667 +k_0=0.99987742 +x_0=600000 +y_0=2200000 +a=6378249.2 +b=6356515",
+		},
+		{
+			"NTF (Paris) / Lambert zone II, numerically",
+			"+proj=lcc +lat_1=46.8 +lat_0=46.8 +lon_0=0 +k_0=0.99987742 +x_0=600000 +y_0=2200000 +a=6378249.2 +b=6356515 +pm=2.337229166666667",
+			"+proj=lcc +lat_1=46.8 +lat_0=46.8 +lon_0=2.337229166666667 +k_0=0.99987742 +x_0=600000 +y_0=2200000 +a=6378249.2 +b=6356515",
+		},
+		{
+			"MGI (Ferro) / Austria GK West Zone",
+			"+proj=tmerc +lat_0=0 +lon_0=28 +k=1 +x_0=0 +y_0=-5000000 +ellps=bessel +pm=ferro",
+			"+proj=tmerc +lat_0=0 +lon_0=10.333333333333334 +k=1 +x_0=0 +y_0=-5000000 +ellps=bessel",
+		},
+		{
+			"MGI (Ferro) / Austria GK West Zone, in DMS",
+			"+proj=tmerc +lat_0=0 +lon_0=28 +k=1 +x_0=0 +y_0=-5000000 +ellps=bessel +pm=17d40'W",
+			"+proj=tmerc +lat_0=0 +lon_0=10.333333333333334 +k=1 +x_0=0 +y_0=-5000000 +ellps=bessel",
+		},
+	} {
+		pj, err := NewProjection(test.defn)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		greenwich, _ := NewProjection(test.greenwich)
+
+		// longitudes in are from Greenwich
+		for _, ll := range [][2]float64{{2.2945, 48.8584}, {-1.5, 47.2}, {9.75, 47.25}} {
+			lng, lat := ll[0]*d2r, ll[1]*d2r
+			x1, y1, err := pj.Forward(lng, lat)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			x2, y2, _ := greenwich.Forward(lng, lat)
+			if !within(x2, x1, 1e-6) || !within(y2, y1, 1e-6) {
+				t.Errorf("%s: expected (%f, %f), got (%f, %f)", test.name, x2, y2, x1, y1)
+			}
+			lng1, lat1, _ := pj.Inverse(x1, y1)
+			if !within(lng, lng1, 1e-11) || !within(lat, lat1, 1e-11) {
+				t.Errorf("%s: inverse off: (%f, %f) - (%f, %f)", test.name, lng, lat, lng1, lat1)
+			}
+		}
+	}
+
+	pj, _ := NewProjection("+proj=lcc +lat_1=46.8 +lat_0=46.8 +a=6378249.2 +b=6356515 +pm=paris")
+	if !within(paris*d2r, pj.FromGreenwich(), 1e-15) {
+		t.Errorf("expected Paris at %v, got %v", paris*d2r, pj.FromGreenwich())
+	}
+	pj, _ = NewProjection("+proj=tmerc +ellps=bessel +pm=ferro")
+	if !within(ferro*d2r, pj.FromGreenwich(), 1e-15) {
+		t.Errorf("expected Ferro at %v, got %v", ferro*d2r, pj.FromGreenwich())
+	}
+
+	// lng/lat are from their own prime meridian
+	ntf, _ := NewProjection("+proj=longlat +a=6378249.2 +b=6356515 +pm=paris")
+	clrk, _ := NewProjection("+proj=longlat +a=6378249.2 +b=6356515")
+	x, y := []float64{0.5 * d2r}, []float64{48 * d2r}
+	if err := Transform(ntf, clrk, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !within((paris+0.5)*d2r, x[0], 1e-15) || !within(48*d2r, y[0], 1e-15) {
+		t.Errorf("expected (%f, 48), got (%f, %f)", paris+0.5, x[0]/d2r, y[0]/d2r)
+	}
+	if err := Transform(clrk, ntf, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !within(0.5*d2r, x[0], 1e-15) {
+		t.Errorf("expected 0.5, got %f", x[0]/d2r)
+	}
+
+	if _, err := NewProjection("+proj=longlat +ellps=WGS84 +pm=atlantis"); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam for an unknown prime meridian, got %v", err)
+	}
+}
+
+func TestLngWrap(t *testing.T) {
+	deg := func(d float64) float64 { return d * d2r }
+	for _, test := range []struct {
+		defn     string
+		lng, exp float64
+	}{
+		{"+proj=longlat +datum=WGS84", -170, -170},
+		{"+proj=longlat +datum=WGS84", 190, -170},
+		{"+proj=longlat +datum=WGS84 +lon_wrap=180", -170, 190},
+		{"+proj=longlat +datum=WGS84 +lon_wrap=180", 170, 170},
+		{"+proj=longlat +datum=WGS84 +lon_wrap=180", 0.5, 0.5},
+		{"+proj=longlat +datum=WGS84 +lon_wrap=180", -0.5, 359.5},
+		{"+proj=longlat +datum=WGS84 +lon_wrap=-180", 10, -350},
+		{"+proj=longlat +datum=WGS84 +lon_wrap=0", 190, -170},
+		{"+proj=longlat +datum=WGS84 +over", 190, 190},
+		{"+proj=longlat +datum=WGS84 +over", -200, -200},
+	} {
+		pj, err := NewProjection(test.defn)
+		if err != nil {
+			t.Fatalf("%s: %v", test.defn, err)
+		}
+		x, _, err := pj.Forward(deg(test.lng), deg(10))
+		if err != nil {
+			t.Errorf("%s: %v", test.defn, err)
+		}
+		if !within(deg(test.exp), x, 1e-12) {
+			t.Errorf("%s: %v should be %v, got %v", test.defn, test.lng, test.exp, x/d2r)
+		}
+		lng, _, _ := pj.Inverse(x, deg(10))
+		if !within(deg(test.exp), lng, 1e-12) {
+			t.Errorf("%s: inverse of %v should be %v, got %v", test.defn, test.exp, test.exp, lng/d2r)
+		}
+	}
+
+	// inverse projections across the antimeridian, in UTM zone 60
+	for _, test := range []struct {
+		defn string
+		exp  float64
+	}{
+		{"+proj=utm +zone=60 +datum=WGS84", -179.5},
+		{"+proj=utm +zone=60 +datum=WGS84 +lon_wrap=180", 180.5},
+		{"+proj=utm +zone=60 +datum=WGS84 +over", 180.5},
+	} {
+		pj, err := NewProjection(test.defn)
+		if err != nil {
+			t.Fatalf("%s: %v", test.defn, err)
+		}
+		x, y, err := pj.Forward(deg(-179.5), deg(-40))
+		if err != nil {
+			t.Fatalf("%s: %v", test.defn, err)
+		}
+		lng, lat, _ := pj.Inverse(x, y)
+		if !within(deg(test.exp), lng, 1e-11) || !within(deg(-40), lat, 1e-11) {
+			t.Errorf("%s: expected (%v, -40), got (%v, %v)", test.defn, test.exp, lng/d2r, lat/d2r)
+		}
+	}
+
+	// +over lets Mercator go round more than once
+	wrapped, _ := NewProjection("+proj=merc +ellps=WGS84")
+	over, _ := NewProjection("+proj=merc +ellps=WGS84 +over")
+	xw, _, _ := wrapped.Forward(deg(190), 0)
+	xo, _, _ := over.Forward(deg(190), 0)
+	if !within(deg(-170)*6378137, xw, 1e-6) || !within(deg(190)*6378137, xo, 1e-6) {
+		t.Errorf("expected %f and %f, got %f and %f", deg(-170)*6378137, deg(190)*6378137, xw, xo)
+	}
+	if lng, _, _ := over.Inverse(xo, 0); !within(deg(190), lng, 1e-12) {
+		t.Errorf("expected 190, got %v", lng/d2r)
+	}
+
+	// and Transform wraps what it returns
+	utm, _ := NewProjection("+proj=utm +zone=60 +datum=WGS84")
+	pacific, _ := NewProjection("+proj=longlat +datum=WGS84 +lon_wrap=180")
+	x, y, _ := utm.Forward(deg(-179.5), deg(-40))
+	xs, ys := []float64{x}, []float64{y}
+	if err := Transform(utm, pacific, xs, ys, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !within(deg(180.5), xs[0], 1e-11) {
+		t.Errorf("expected 180.5, got %v", xs[0]/d2r)
+	}
+}
@@ -5,11 +5,12 @@
 package projectron
 
 import "math"
-import "errors"
+import "strconv"
 
 type impl interface {
 	Projection
 	init(paramset) error
+	base() *pj
 }
 
 func lookupImpl(pin *pj) impl {
@@ -22,10 +23,41 @@ func lookupImpl(pin *pj) impl {
 		return &LCC{pj: pin}
 	case "eqc":
 		return &Equirectangular{pj: pin}
+	case "tmerc":
+		return &TransverseMercator{pj: pin}
+	case "utm":
+		return &TransverseMercator{pj: pin, utm: true}
+	case "stere":
+		return &Stereographic{pj: pin}
+	case "sterea":
+		return &ObliqueStereographic{pj: pin}
+	case "somerc":
+		return &SwissObliqueMercator{pj: pin}
+	case "aea":
+		return &AlbersEqualArea{pj: pin}
+	case "laea":
+		return &LambertAzimuthalEqualArea{pj: pin}
+	case "aeqd":
+		return &AzimuthalEquidistant{pj: pin}
+	case "ortho":
+		return &Orthographic{pj: pin}
+	case "gnom":
+		return &Gnomonic{pj: pin}
+	case "geocent":
+		return &Geocentric{pin}
 	}
 	return nil
 }
 
+// isLngLat reports whether proj is one of the names for LngLat.
+func isLngLat(proj string) bool {
+	switch proj {
+	case "latlong", "longlat", "latlon", "lonlat":
+		return true
+	}
+	return false
+}
+
 type LngLat struct {
 	*pj
 }
@@ -49,7 +81,7 @@ func (ll *LngLat) Inverse(x, y float64) (lng, lat float64, err error) {
 }
 
 func (ll *LngLat) fwd(lam, phi float64) (float64, float64, error) {
-	x := lam / ll.a
+	x := ll.wrapLng(lam) / ll.a
 	y := phi / ll.a
 	return x, y, nil
 }
@@ -60,12 +92,37 @@ func (ll *LngLat) inv(x, y float64) (lng, lat float64, err error) {
 	return lng, lat, nil
 }
 
+// Geocentric implements +proj=geocent, Earth-centred, Earth-fixed XYZ.
+// There's no planar form, so Forward and Inverse always fail; use it as
+// either end of a Transform instead.
+type Geocentric struct {
+	*pj
+}
+
+func (g *Geocentric) init(params paramset) error {
+	g.x0 = 0
+	g.y0 = 0
+	return nil
+}
+
+func (g *Geocentric) IsLngLat() bool {
+	return false
+}
+
+func (g *Geocentric) Forward(lng, lat float64) (x, y float64, err error) {
+	return hugeVal, hugeVal, &ProjectionError{Code: ErrCodeAPIMisuse, Proj: g.proj, Err: ErrGeocentric}
+}
+
+func (g *Geocentric) Inverse(x, y float64) (lng, lat float64, err error) {
+	return hugeVal, hugeVal, &ProjectionError{Code: ErrCodeAPIMisuse, Proj: g.proj, Err: ErrGeocentric}
+}
+
 type Mercator struct {
 	*pj
 }
 
 func (m *Mercator) IsLngLat() bool {
-	return true
+	return false
 }
 func (m *Mercator) ToMeter() float64 {
 	return m.to_meter
@@ -109,8 +166,8 @@ func (m *Mercator) fwd(lam, phi float64) (x float64, y float64, err error) {
 
 func (m *Mercator) inv(x, y float64) (lng, lat float64, err error) {
 	if m.es != 0 {
-		lat, err = phi2(math.Exp(-y/m.k0), m.e)
-		lng = x * m.k0
+		lat, err = phi2(m.e, math.Exp(-y/m.k0))
+		lng = x / m.k0
 	} else {
 		lng = x / m.k0
 	lat = half_pi - 2*math.Atan(math.Exp(-y/m.k0))
@@ -120,6 +177,395 @@ func (m *Mercator) inv(x, y float64) (lng, lat float64, err error) {
 }
 
 
+type aspect int
+
+const (
+	southPole aspect = iota
+	northPole
+	oblique
+	equatorial
+)
+
+// aspectOf picks the aspect of an azimuthal projection centred on phi0.
+func aspectOf(phi0 float64) aspect {
+	t := math.Abs(phi0)
+	if math.Abs(t-half_pi) < epsln {
+		if phi0 < 0 {
+			return southPole
+		}
+		return northPole
+	} else if t > epsln {
+		return oblique
+	}
+	return equatorial
+}
+
+// Stereographic implements +proj=stere in its polar, equatorial and
+// oblique aspects.  On the ellipsoid the oblique aspect uses conformal
+// latitudes; see ObliqueStereographic for the "double" variant.
+type Stereographic struct {
+	*pj
+	mode         aspect
+	phits        float64
+	sinX1, cosX1 float64
+	akm1         float64
+}
+
+func (st *Stereographic) IsLngLat() bool {
+	return false
+}
+
+func ssfn(phit, sinphi, e float64) float64 {
+	sinphi *= e
+	return math.Tan(.5*(half_pi+phit)) * math.Pow((1-sinphi)/(1+sinphi), .5*e)
+}
+
+func (st *Stereographic) init(params paramset) error {
+	var ok bool
+	if st.phits, ok = params.degree("lat_ts"); !ok {
+		st.phits = half_pi
+	}
+	st.phits = math.Abs(st.phits)
+	st.mode = aspectOf(st.phi0)
+
+	if st.es != 0 {
+		switch st.mode {
+		case northPole, southPole:
+			if math.Abs(st.phits-half_pi) < epsln {
+				st.akm1 = 2 * st.k0 / math.Sqrt(math.Pow(1+st.e, 1+st.e)*math.Pow(1-st.e, 1-st.e))
+			} else {
+				t := math.Sin(st.phits)
+				st.akm1 = math.Cos(st.phits) / tsfn(st.phits, t, st.e)
+				t *= st.e
+				st.akm1 /= math.Sqrt(1 - t*t)
+			}
+		case equatorial, oblique:
+			t := math.Sin(st.phi0)
+			x := 2*math.Atan(ssfn(st.phi0, t, st.e)) - half_pi
+			t *= st.e
+			st.akm1 = 2 * st.k0 * math.Cos(st.phi0) / math.Sqrt(1-t*t)
+			st.sinX1 = math.Sin(x)
+			st.cosX1 = math.Cos(x)
+		}
+	} else {
+		switch st.mode {
+		case oblique:
+			st.sinX1 = math.Sin(st.phi0)
+			st.cosX1 = math.Cos(st.phi0)
+			st.akm1 = 2 * st.k0
+		case equatorial:
+			st.akm1 = 2 * st.k0
+		case northPole, southPole:
+			if math.Abs(st.phits-half_pi) >= epsln {
+				st.akm1 = math.Cos(st.phits) / math.Tan(fort_pi-.5*st.phits)
+			} else {
+				st.akm1 = 2 * st.k0
+			}
+		}
+	}
+	return nil
+}
+
+func (st *Stereographic) Forward(lng, lat float64) (x, y float64, err error) {
+	return st.commonFwd(lng, lat, st.fwd)
+}
+
+func (st *Stereographic) Inverse(x, y float64) (lng, lat float64, err error) {
+	return st.commonInv(x, y, st.inv)
+}
+
+func (st *Stereographic) fwd(lam, phi float64) (x, y float64, err error) {
+	if st.es == 0 {
+		return st.sphereFwd(lam, phi)
+	}
+	coslam, sinlam := math.Cos(lam), math.Sin(lam)
+	sinphi := math.Sin(phi)
+	var sinX, cosX float64
+	if st.mode == oblique || st.mode == equatorial {
+		X := 2*math.Atan(ssfn(phi, sinphi, st.e)) - half_pi
+		sinX, cosX = math.Sin(X), math.Cos(X)
+	}
+	switch st.mode {
+	case oblique:
+		denom := st.cosX1 * (1 + st.sinX1*sinX + st.cosX1*cosX*coslam)
+		if denom == 0 {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		a := st.akm1 / denom
+		y = a * (st.cosX1*sinX - st.sinX1*cosX*coslam)
+		x = a * cosX
+	case equatorial:
+		denom := 1 + cosX*coslam
+		if denom == 0 {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		a := st.akm1 / denom
+		y = a * sinX
+		x = a * cosX
+	case southPole, northPole:
+		if st.mode == southPole {
+			phi, coslam, sinphi = -phi, -coslam, -sinphi
+		}
+		if math.Abs(phi-half_pi) < 1e-15 {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		x = st.akm1 * tsfn(phi, sinphi, st.e)
+		y = -x * coslam
+	}
+	return x * sinlam, y, nil
+}
+
+func (st *Stereographic) sphereFwd(lam, phi float64) (x, y float64, err error) {
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	coslam, sinlam := math.Cos(lam), math.Sin(lam)
+	switch st.mode {
+	case equatorial, oblique:
+		if st.mode == equatorial {
+			y = 1 + cosphi*coslam
+		} else {
+			y = 1 + st.sinX1*sinphi + st.cosX1*cosphi*coslam
+		}
+		if y <= epsln {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		y = st.akm1 / y
+		x = y * cosphi * sinlam
+		if st.mode == equatorial {
+			y *= sinphi
+		} else {
+			y *= st.cosX1*sinphi - st.sinX1*cosphi*coslam
+		}
+	case northPole, southPole:
+		if st.mode == northPole {
+			coslam, phi = -coslam, -phi
+		}
+		if math.Abs(phi-half_pi) < 1e-8 {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		y = st.akm1 * math.Tan(fort_pi+.5*phi)
+		x = sinlam * y
+		y *= coslam
+	}
+	return x, y, nil
+}
+
+func (st *Stereographic) inv(x, y float64) (lng, lat float64, err error) {
+	if st.es == 0 {
+		return st.sphereInv(x, y)
+	}
+	var tp, phiL, halfe, halfpi float64
+	rho := math.Hypot(x, y)
+	switch st.mode {
+	case oblique, equatorial:
+		tp = 2 * math.Atan2(rho*st.cosX1, st.akm1)
+		cosphi, sinphi := math.Cos(tp), math.Sin(tp)
+		if rho == 0 {
+			phiL = math.Asin(cosphi * st.sinX1)
+		} else {
+			phiL = math.Asin(cosphi*st.sinX1 + (y * sinphi * st.cosX1 / rho))
+		}
+		tp = math.Tan(.5 * (half_pi + phiL))
+		x *= sinphi
+		y = rho*st.cosX1*cosphi - y*st.sinX1*sinphi
+		halfpi = half_pi
+		halfe = .5 * st.e
+	case northPole, southPole:
+		if st.mode == northPole {
+			y = -y
+		}
+		tp = -rho / st.akm1
+		phiL = half_pi - 2*math.Atan(tp)
+		halfpi = -half_pi
+		halfe = -.5 * st.e
+	}
+	for i := 0; i < 8; i++ {
+		sinphi := st.e * math.Sin(phiL)
+		phi := 2*math.Atan(tp*math.Pow((1+sinphi)/(1-sinphi), halfe)) - halfpi
+		if math.Abs(phiL-phi) < 1e-10 {
+			if st.mode == southPole {
+				phi = -phi
+			}
+			if x != 0 || y != 0 {
+				lng = math.Atan2(x, y)
+			}
+			return lng, phi, nil
+		}
+		phiL = phi
+	}
+	return hugeVal, hugeVal, ErrNoConvergence
+}
+
+func (st *Stereographic) sphereInv(x, y float64) (lng, lat float64, err error) {
+	rh := math.Hypot(x, y)
+	c := 2 * math.Atan(rh/st.akm1)
+	sinc, cosc := math.Sin(c), math.Cos(c)
+	switch st.mode {
+	case equatorial:
+		if math.Abs(rh) > epsln {
+			lat = math.Asin(y * sinc / rh)
+		}
+		if cosc != 0 || x != 0 {
+			lng = math.Atan2(x*sinc, cosc*rh)
+		}
+	case oblique:
+		if math.Abs(rh) <= epsln {
+			lat = st.phi0
+		} else {
+			lat = math.Asin(cosc*st.sinX1 + y*sinc*st.cosX1/rh)
+		}
+		c = cosc - st.sinX1*math.Sin(lat)
+		if c != 0 || x != 0 {
+			lng = math.Atan2(x*sinc*st.cosX1, c*rh)
+		}
+	case northPole, southPole:
+		if st.mode == northPole {
+			y = -y
+		}
+		if math.Abs(rh) <= epsln {
+			lat = st.phi0
+		} else if st.mode == southPole {
+			lat = math.Asin(-cosc)
+		} else {
+			lat = math.Asin(cosc)
+		}
+		if x != 0 || y != 0 {
+			lng = math.Atan2(x, y)
+		}
+	}
+	return lng, lat, nil
+}
+
+// ObliqueStereographic implements +proj=sterea, the "double" stereographic
+// which first maps the ellipsoid conformally onto the Gaussian sphere and
+// then projects that stereographically.  It's the basis of the Dutch RD
+// and several other national grids.
+type ObliqueStereographic struct {
+	*pj
+	gauss        *gauss
+	phic0        float64
+	sinc0, cosc0 float64
+	r2           float64
+}
+
+func (st *ObliqueStereographic) IsLngLat() bool {
+	return false
+}
+
+func (st *ObliqueStereographic) init(params paramset) error {
+	var r float64
+	var err error
+	if st.gauss, st.phic0, r, err = newGauss(st.e, st.phi0); err != nil {
+		return err
+	}
+	st.sinc0 = math.Sin(st.phic0)
+	st.cosc0 = math.Cos(st.phic0)
+	st.r2 = 2 * r
+	return nil
+}
+
+func (st *ObliqueStereographic) Forward(lng, lat float64) (x, y float64, err error) {
+	return st.commonFwd(lng, lat, st.fwd)
+}
+
+func (st *ObliqueStereographic) Inverse(x, y float64) (lng, lat float64, err error) {
+	return st.commonInv(x, y, st.inv)
+}
+
+func (st *ObliqueStereographic) fwd(lam, phi float64) (x, y float64, err error) {
+	lam, phi = st.gauss.fwd(lam, phi)
+	sinc, cosc := math.Sin(phi), math.Cos(phi)
+	cosl := math.Cos(lam)
+	denom := 1 + st.sinc0*sinc + st.cosc0*cosc*cosl
+	if denom == 0 {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	k := st.k0 * st.r2 / denom
+	x = k * cosc * math.Sin(lam)
+	y = k * (st.cosc0*sinc - st.sinc0*cosc*cosl)
+	return x, y, nil
+}
+
+func (st *ObliqueStereographic) inv(x, y float64) (lng, lat float64, err error) {
+	x /= st.k0
+	y /= st.k0
+	if rho := math.Hypot(x, y); rho != 0 {
+		c := 2 * math.Atan2(rho, st.r2)
+		sinc, cosc := math.Sin(c), math.Cos(c)
+		lat = math.Asin(cosc*st.sinc0 + y*sinc*st.cosc0/rho)
+		lng = math.Atan2(x*sinc, rho*st.cosc0*cosc-y*st.sinc0*sinc)
+	} else {
+		lat = st.phic0
+	}
+	return st.gauss.inv(lng, lat)
+}
+
+// SwissObliqueMercator implements +proj=somerc, the oblique Mercator of
+// the Swiss and Hungarian grids: the ellipsoid is mapped conformally onto a
+// sphere, which is rotated to put the origin on the equator and then
+// projected with the spherical Mercator.
+type SwissObliqueMercator struct {
+	*pj
+	k, c, hlfE, kR float64
+	cosp0, sinp0   float64
+}
+
+func (so *SwissObliqueMercator) IsLngLat() bool {
+	return false
+}
+
+func (so *SwissObliqueMercator) init(params paramset) error {
+	so.hlfE = .5 * so.e
+	cp := math.Cos(so.phi0)
+	cp *= cp
+	so.c = math.Sqrt(1 + so.es*cp*cp*so.rOneEs)
+	sp := math.Sin(so.phi0)
+	so.sinp0 = sp / so.c
+	phip0 := aasin(so.sinp0)
+	so.cosp0 = math.Cos(phip0)
+	sp *= so.e
+	so.k = math.Log(math.Tan(fort_pi+.5*phip0)) -
+		so.c*(math.Log(math.Tan(fort_pi+.5*so.phi0))-so.hlfE*math.Log((1+sp)/(1-sp)))
+	so.kR = so.k0 * math.Sqrt(so.oneEs) / (1 - sp*sp)
+	return nil
+}
+
+func (so *SwissObliqueMercator) Forward(lng, lat float64) (x, y float64, err error) {
+	return so.commonFwd(lng, lat, so.fwd)
+}
+
+func (so *SwissObliqueMercator) Inverse(x, y float64) (lng, lat float64, err error) {
+	return so.commonInv(x, y, so.inv)
+}
+
+func (so *SwissObliqueMercator) fwd(lam, phi float64) (x, y float64, err error) {
+	sp := so.e * math.Sin(phi)
+	phip := 2*math.Atan(math.Exp(so.c*(math.Log(math.Tan(fort_pi+.5*phi))-so.hlfE*math.Log((1+sp)/(1-sp)))+so.k)) - half_pi
+	lamp := so.c * lam
+	cp := math.Cos(phip)
+	phipp := aasin(so.cosp0*math.Sin(phip) - so.sinp0*cp*math.Cos(lamp))
+	lampp := aasin(cp * math.Sin(lamp) / math.Cos(phipp))
+	return so.kR * lampp, so.kR * math.Log(math.Tan(fort_pi+.5*phipp)), nil
+}
+
+func (so *SwissObliqueMercator) inv(x, y float64) (lng, lat float64, err error) {
+	phipp := 2 * (math.Atan(math.Exp(y/so.kR)) - fort_pi)
+	lampp := x / so.kR
+	cp := math.Cos(phipp)
+	phip := aasin(so.cosp0*math.Sin(phipp) + so.sinp0*cp*math.Cos(lampp))
+	lamp := aasin(cp * math.Sin(lampp) / math.Cos(phip))
+	con := (so.k - math.Log(math.Tan(fort_pi+.5*phip))) / so.c
+	for i := 0; i < 6; i++ {
+		esp := so.e * math.Sin(phip)
+		delp := (con + math.Log(math.Tan(fort_pi+.5*phip)) - so.hlfE*math.Log((1+esp)/(1-esp))) *
+			(1 - esp*esp) * math.Cos(phip) / so.oneEs
+		phip -= delp
+		if math.Abs(delp) < 1e-10 {
+			return lamp / so.c, phip, nil
+		}
+	}
+	return hugeVal, hugeVal, noConvergence("somerc")
+}
+
 type LCC struct {
 	*pj
 	c, n, rho0 float64
@@ -142,7 +588,7 @@ func (ll *LCC) init(params paramset) error {
 		}
 	}
 	if math.Abs(ll.phi1 + ll.phi2) <= epsln {
-		return errors.New("these can't be the same")
+		return ll.paramError("lat_1", ErrInvalidParam)
 	}
 	sinphi := math.Sin(ll.phi1)
 	ll.n = sinphi
@@ -192,8 +638,9 @@ func (ll *LCC) Inverse(x, y float64) (lng, lat float64, err error) {
 func (ll *LCC) fwd(lam, phi float64) (x float64, y float64, err error) {
 	var rho float64
 	if math.Abs(math.Abs(phi)-half_pi) < epsln {
+		// the pole on the far side of the cone projects to infinity
 		if phi*ll.n <= 0 {
-			return hugeVal, hugeVal, errors.New("something")
+			return hugeVal, hugeVal, ErrToleranceCondition
 		}
 	} else {
 		if ll.ellips {
@@ -209,16 +656,46 @@ func (ll *LCC) fwd(lam, phi float64) (x float64, y float64, err error) {
 }
 
 func (ll *LCC) inv(x, y float64) (lng, lat float64, err error) {
-	panic("don't call this")
+	x /= ll.k0
+	y = ll.rho0 - y/ll.k0
+	rho := math.Hypot(x, y)
+	if rho < epsln {
+		// the apex of the cone is the pole on the side of the tangent
+		return 0, math.Copysign(half_pi, ll.n), nil
+	}
+	if ll.n < 0 {
+		rho, x, y = -rho, -x, -y
+	}
+	if ll.ellips {
+		lat, err = phi2(ll.e, math.Pow(rho/ll.c, 1/ll.n))
+		if err != nil {
+			return hugeVal, hugeVal, err
+		}
+	} else {
+		lat = 2*math.Atan(math.Pow(ll.c/rho, 1/ll.n)) - half_pi
+	}
+	lng = math.Atan2(x, y) / ll.n
+	return lng, lat, nil
 }
 
+// Equirectangular implements +proj=eqc, the equidistant cylindrical (Plate
+// Carrée when lat_ts is 0).  Like PROJ it is only defined on the sphere,
+// with a as the radius.
 type Equirectangular struct {
 	*pj
 	phi1 float64
+	rc   float64
 }
 
 func (eqc *Equirectangular) init(params paramset) error {
-	eqc.phi1, _ = params.degree("lat_1")
+	var ok bool
+	if eqc.phi1, ok = params.degree("lat_ts"); !ok {
+		eqc.phi1, _ = params.degree("lat_1")
+	}
+	if eqc.rc = math.Cos(eqc.phi1); eqc.rc <= epsln {
+		return eqc.paramError("lat_ts", ErrInvalidParam)
+	}
+	eqc.es, eqc.e = 0, 0
 	return nil
 }
 
@@ -234,14 +711,1146 @@ func (eqc *Equirectangular) Inverse(x, y float64) (lng, lat float64, err error)
 }
 
 func (eqc *Equirectangular) fwd(lam, phi float64) (float64, float64, error) {
-	x := lam / eqc.a
-	y := phi / eqc.a
+	x := eqc.rc * lam
+	y := phi - eqc.phi0
 	return x, y, nil
 }
 
 func (eqc *Equirectangular) inv(x, y float64) (lng, lat float64, err error) {
-	lat = y * eqc.a
-	lng = x * math.Cos(eqc.phi1) * eqc.a
+	lng = x / eqc.rc
+	lat = y + eqc.phi0
 	return lng, lat, nil
 }
 
+// TransverseMercator implements +proj=tmerc.  By default it uses the
+// Poder/Engsager formulation of the Krüger series, which is accurate to
+// well under a millimetre out to several thousand kilometres from the
+// central meridian.  +approx selects the classic Snyder series instead,
+// which is faster but degrades quickly away from the central meridian.
+//
+// TransverseMercator also implements +proj=utm, in which case the central
+// meridian, scale and false easting/northing come from +zone and +south.
+type TransverseMercator struct {
+	*pj
+	approx bool
+	utm    bool
+	// Snyder
+	esp, ml0 float64
+	en       [5]float64
+	// Poder/Engsager
+	qn, zb   float64
+	cgb, cbg []float64
+	utg, gtu []float64
+}
+
+const (
+	tmercOrder = 6
+	// the Krüger series are only valid within ~150 degrees of the
+	// central meridian
+	tmercMaxCe = 2.623395162778
+)
+
+func (tm *TransverseMercator) IsLngLat() bool {
+	return false
+}
+
+func (tm *TransverseMercator) init(params paramset) error {
+	if tm.utm {
+		if err := tm.initUTM(params); err != nil {
+			return err
+		}
+	}
+	tm.approx, _ = params.bool("approx")
+	// the exact algorithm has no spherical form
+	if tm.approx || tm.es == 0 {
+		tm.approx = true
+		if tm.es != 0 {
+			tm.en = enfn(tm.es)
+			tm.ml0 = mlfn(tm.phi0, math.Sin(tm.phi0), math.Cos(tm.phi0), tm.en)
+			tm.esp = tm.es / (1 - tm.es)
+		} else {
+			tm.esp = tm.k0
+			tm.ml0 = .5 * tm.esp
+		}
+		return nil
+	}
+
+	// third flattening
+	f := tm.es / (1 + math.Sqrt(1-tm.es))
+	n := f / (2 - f)
+	np := n
+
+	tm.cgb = make([]float64, tmercOrder)
+	tm.cbg = make([]float64, tmercOrder)
+	tm.utg = make([]float64, tmercOrder)
+	tm.gtu = make([]float64, tmercOrder)
+
+	// Gaussian <-> geodetic latitude, KW p186 - 191
+	tm.cgb[0] = n * (2 + n*(-2/3.0+n*(-2+n*(116/45.0+n*(26/45.0+n*(-2854/675.0))))))
+	tm.cbg[0] = n * (-2 + n*(2/3.0+n*(4/3.0+n*(-82/45.0+n*(32/45.0+n*(4642/4725.0))))))
+	np *= n
+	tm.cgb[1] = np * (7/3.0 + n*(-8/5.0+n*(-227/45.0+n*(2704/315.0+n*(2323/945.0)))))
+	tm.cbg[1] = np * (5/3.0 + n*(-16/15.0+n*(-13/9.0+n*(904/315.0+n*(-1522/945.0)))))
+	np *= n
+	tm.cgb[2] = np * (56/15.0 + n*(-136/35.0+n*(-1262/105.0+n*(73814/2835.0))))
+	tm.cbg[2] = np * (-26/15.0 + n*(34/21.0+n*(8/5.0+n*(-12686/2835.0))))
+	np *= n
+	tm.cgb[3] = np * (4279/630.0 + n*(-332/35.0+n*(-399572/14175.0)))
+	tm.cbg[3] = np * (1237/630.0 + n*(-12/5.0+n*(-24832/14175.0)))
+	np *= n
+	tm.cgb[4] = np * (4174/315.0 + n*(-144838/6237.0))
+	tm.cbg[4] = np * (-734/315.0 + n*(109598/31185.0))
+	np *= n
+	tm.cgb[5] = np * (601676 / 22275.0)
+	tm.cbg[5] = np * (444337 / 155925.0)
+
+	// normalized meridian quadrant, KW p.50 (96)
+	np = n * n
+	tm.qn = tm.k0 / (1 + n) * (1 + np*(1/4.0+np*(1/64.0+np/256.0)))
+
+	// ellipsoidal <-> spherical northing/easting, KW p194 - 196
+	tm.utg[0] = n * (-0.5 + n*(2/3.0+n*(-37/96.0+n*(1/360.0+n*(81/512.0+n*(-96199/604800.0))))))
+	tm.gtu[0] = n * (0.5 + n*(-2/3.0+n*(5/16.0+n*(41/180.0+n*(-127/288.0+n*(7891/37800.0))))))
+	tm.utg[1] = np * (-1/48.0 + n*(-1/15.0+n*(437/1440.0+n*(-46/105.0+n*(1118711/3870720.0)))))
+	tm.gtu[1] = np * (13/48.0 + n*(-3/5.0+n*(557/1440.0+n*(281/630.0+n*(-1983433/1935360.0)))))
+	np *= n
+	tm.utg[2] = np * (-17/480.0 + n*(37/840.0+n*(209/4480.0+n*(-5569/90720.0))))
+	tm.gtu[2] = np * (61/240.0 + n*(-103/140.0+n*(15061/26880.0+n*(167603/181440.0))))
+	np *= n
+	tm.utg[3] = np * (-4397/161280.0 + n*(11/504.0+n*(830251/7257600.0)))
+	tm.gtu[3] = np * (49561/161280.0 + n*(-179/168.0+n*(6601661/7257600.0)))
+	np *= n
+	tm.utg[4] = np * (-4583/161280.0 + n*(108847/3991680.0))
+	tm.gtu[4] = np * (34729/80640.0 + n*(-3418889/1995840.0))
+	np *= n
+	tm.utg[5] = np * (-20648693 / 638668800.0)
+	tm.gtu[5] = np * (212378941 / 319334400.0)
+
+	// origin northing minus true northing at the origin latitude
+	z := gatg(tm.cbg, tm.phi0)
+	tm.zb = -tm.qn * (z + clens(tm.gtu, 2*z))
+	return nil
+}
+
+func (tm *TransverseMercator) initUTM(params paramset) error {
+	if tm.es == 0 {
+		// UTM is only defined on an ellipsoid
+		return tm.paramError("ellps", ErrInvalidParam)
+	}
+	var zone int
+	if s, ok := params.string("zone"); ok {
+		var err error
+		if zone, err = strconv.Atoi(s); err != nil || zone < 1 || zone > 60 {
+			return tm.paramError("zone", ErrInvalidParam)
+		}
+	} else {
+		// no zone given, so guess it from the central meridian
+		zone = int(math.Floor((adjLng(tm.lam0)+math.Pi)*30/math.Pi)) + 1
+		zone = int(math.Max(1, math.Min(60, float64(zone))))
+	}
+	tm.x0 = 500000
+	tm.y0 = 0
+	if south, _ := params.bool("south"); south {
+		tm.y0 = 10000000
+	}
+	tm.lam0 = (float64(zone-1)+.5)*math.Pi/30 - math.Pi
+	tm.k0 = 0.9996
+	tm.phi0 = 0
+	return nil
+}
+
+// UTMZone returns the UTM zone containing lng/lat (in radians), and
+// whether it falls in the southern hemisphere.  The zones around
+// southwestern Norway and Svalbard are widened as in the standard grid.
+func UTMZone(lng, lat float64) (zone int, south bool) {
+	lng, lat = adjLng(lng)/d2r, lat/d2r
+	if lng >= 180 {
+		lng -= 360
+	}
+	zone = int(math.Floor((lng+180)/6)) + 1
+	if lat >= 56 && lat < 64 && lng >= 3 && lng < 12 {
+		zone = 32
+	} else if lat >= 72 && lat < 84 && lng >= 0 && lng < 42 {
+		switch {
+		case lng < 9:
+			zone = 31
+		case lng < 21:
+			zone = 33
+		case lng < 33:
+			zone = 35
+		default:
+			zone = 37
+		}
+	}
+	return zone, lat < 0
+}
+
+func (tm *TransverseMercator) Forward(lng, lat float64) (x, y float64, err error) {
+	return tm.commonFwd(lng, lat, tm.fwd)
+}
+
+func (tm *TransverseMercator) Inverse(x, y float64) (lng, lat float64, err error) {
+	return tm.commonInv(x, y, tm.inv)
+}
+
+func (tm *TransverseMercator) fwd(lam, phi float64) (x, y float64, err error) {
+	if !tm.approx {
+		return tm.exactFwd(lam, phi)
+	} else if tm.es == 0 {
+		return tm.sphereFwd(lam, phi)
+	}
+	return tm.approxFwd(lam, phi)
+}
+
+func (tm *TransverseMercator) inv(x, y float64) (lng, lat float64, err error) {
+	if !tm.approx {
+		return tm.exactInv(x, y)
+	} else if tm.es == 0 {
+		return tm.sphereInv(x, y)
+	}
+	return tm.approxInv(x, y)
+}
+
+func (tm *TransverseMercator) exactFwd(lam, phi float64) (x, y float64, err error) {
+	// ellipsoidal lat, lng -> Gaussian lat, lng
+	cn := gatg(tm.cbg, phi)
+	// Gaussian lat, lng -> complementary spherical lat
+	sinCn, cosCn := math.Sin(cn), math.Cos(cn)
+	sinCe, cosCe := math.Sin(lam), math.Cos(lam)
+	cn = math.Atan2(sinCn, cosCe*cosCn)
+	ce := math.Atan2(sinCe*cosCn, math.Hypot(sinCn, cosCn*cosCe))
+	// complementary spherical N, E -> ellipsoidal normalized N, E
+	ce = math.Asinh(math.Tan(ce))
+	dCn, dCe := clenS(tm.gtu, 2*cn, 2*ce)
+	cn += dCn
+	ce += dCe
+	if math.Abs(ce) > tmercMaxCe {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	return tm.qn * ce, tm.qn*cn + tm.zb, nil
+}
+
+func (tm *TransverseMercator) exactInv(x, y float64) (lng, lat float64, err error) {
+	// normalize N, E
+	cn := (y - tm.zb) / tm.qn
+	ce := x / tm.qn
+	if math.Abs(ce) > tmercMaxCe {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	// normalized N, E -> complementary spherical lat, lng
+	dCn, dCe := clenS(tm.utg, 2*cn, 2*ce)
+	cn += dCn
+	ce += dCe
+	ce = math.Atan(math.Sinh(ce))
+	// complementary spherical lat -> Gaussian lat, lng
+	sinCn, cosCn := math.Sin(cn), math.Cos(cn)
+	sinCe, cosCe := math.Sin(ce), math.Cos(ce)
+	ce = math.Atan2(sinCe, cosCe*cosCn)
+	cn = math.Atan2(sinCn*cosCe, math.Hypot(sinCe, cosCe*cosCn))
+	// Gaussian lat, lng -> ellipsoidal lat, lng
+	return ce, gatg(tm.cgb, cn), nil
+}
+
+const (
+	fc1 = 1.
+	fc2 = .5
+	fc3 = .16666666666666666666
+	fc4 = .08333333333333333333
+	fc5 = .05
+	fc6 = .03333333333333333333
+	fc7 = .02380952380952380952
+	fc8 = .01785714285714285714
+)
+
+func (tm *TransverseMercator) approxFwd(lam, phi float64) (x, y float64, err error) {
+	if lam < -half_pi || lam > half_pi {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	var t float64
+	if math.Abs(cosphi) > 1e-10 {
+		t = sinphi / cosphi
+	}
+	t *= t
+	al := cosphi * lam
+	als := al * al
+	al /= math.Sqrt(1 - tm.es*sinphi*sinphi)
+	n := tm.esp * cosphi * cosphi
+	x = tm.k0 * al * (fc1 +
+		fc3*als*(1-t+n+
+			fc5*als*(5+t*(t-18)+n*(14-58*t)+
+				fc7*als*(61+t*(t*(179-t)-479)))))
+	y = tm.k0 * (mlfn(phi, sinphi, cosphi, tm.en) - tm.ml0 +
+		sinphi*al*lam*fc2*(1+
+			fc4*als*(5-t+n*(9+4*n)+
+				fc6*als*(61+t*(t-58)+n*(270-330*t)+
+					fc8*als*(1385+t*(t*(543-t)-3111))))))
+	return x, y, nil
+}
+
+func (tm *TransverseMercator) approxInv(x, y float64) (lng, lat float64, err error) {
+	lat, err = invMlfn(tm.ml0+y/tm.k0, tm.es, tm.en)
+	if err != nil {
+		return hugeVal, hugeVal, err
+	}
+	if math.Abs(lat) >= half_pi {
+		return 0, math.Copysign(half_pi, y), nil
+	}
+	sinphi, cosphi := math.Sin(lat), math.Cos(lat)
+	var t float64
+	if math.Abs(cosphi) > 1e-10 {
+		t = sinphi / cosphi
+	}
+	n := tm.esp * cosphi * cosphi
+	con := 1 - tm.es*sinphi*sinphi
+	d := math.Sqrt(con)
+	con *= t
+	t *= t
+	d = x * d / tm.k0
+	ds := d * d
+	lat -= (con * ds / (1 - tm.es)) * fc2 * (1 -
+		ds*fc4*(5+t*(3-9*n)+n*(1-4*n)-
+			ds*fc6*(61+t*(90-252*n+45*t)+46*n-
+				ds*fc8*(1385+t*(3633+t*(4095+1575*t))))))
+	lng = d * (fc1 -
+		ds*fc3*(1+2*t+n-
+			ds*fc5*(5+t*(28+24*t+8*n)+6*n-
+				ds*fc7*(61+t*(662+t*(1320+720*t)))))) / cosphi
+	return lng, lat, nil
+}
+
+func (tm *TransverseMercator) sphereFwd(lam, phi float64) (x, y float64, err error) {
+	cosphi := math.Cos(phi)
+	b := cosphi * math.Sin(lam)
+	if math.Abs(math.Abs(b)-1) <= epsln {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	x = tm.ml0 * math.Log((1+b)/(1-b))
+	y = cosphi * math.Cos(lam) / math.Sqrt(1-b*b)
+	b = math.Abs(y)
+	if b >= 1 {
+		if b-1 > epsln {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		y = 0
+	} else {
+		y = math.Acos(y)
+	}
+	if phi < 0 {
+		y = -y
+	}
+	y = tm.esp * (y - tm.phi0)
+	return x, y, nil
+}
+
+func (tm *TransverseMercator) sphereInv(x, y float64) (lng, lat float64, err error) {
+	h := math.Exp(x / tm.esp)
+	g := .5 * (h - 1/h)
+	h = math.Cos(tm.phi0 + y/tm.esp)
+	lat = math.Asin(math.Sqrt((1 - h*h) / (1 + g*g)))
+	// make sure that phi is on the correct hemisphere when false northing is used
+	if y < 0 && -lat+tm.phi0 < 0 {
+		lat = -lat
+	}
+	if g != 0 || h != 0 {
+		lng = math.Atan2(g, h)
+	}
+	return lng, lat, nil
+}
+
+// AlbersEqualArea implements +proj=aea, the equal area conic with two
+// standard parallels.
+type AlbersEqualArea struct {
+	*pj
+	ec, n, c, dd, n2, rho0 float64
+	phi1, phi2             float64
+	ellips                 bool
+}
+
+func (aea *AlbersEqualArea) IsLngLat() bool {
+	return false
+}
+
+func (aea *AlbersEqualArea) init(params paramset) error {
+	aea.phi1, _ = params.degree("lat_1")
+	aea.phi2, _ = params.degree("lat_2")
+	if math.Abs(aea.phi1) > half_pi {
+		return aea.paramError("lat_1", ErrInvalidParam)
+	}
+	if math.Abs(aea.phi2) > half_pi || math.Abs(aea.phi1+aea.phi2) < epsln {
+		return aea.paramError("lat_2", ErrInvalidParam)
+	}
+	sinphi := math.Sin(aea.phi1)
+	cosphi := math.Cos(aea.phi1)
+	aea.n = sinphi
+	secant := math.Abs(aea.phi1-aea.phi2) >= epsln
+	aea.ellips = aea.es > 0
+	if aea.ellips {
+		m1 := msfn(sinphi, cosphi, aea.es)
+		ml1 := qsfn(sinphi, aea.e, aea.oneEs)
+		if secant {
+			sinphi = math.Sin(aea.phi2)
+			cosphi = math.Cos(aea.phi2)
+			m2 := msfn(sinphi, cosphi, aea.es)
+			ml2 := qsfn(sinphi, aea.e, aea.oneEs)
+			if ml2 == ml1 {
+				return aea.paramError("lat_2", ErrInvalidParam)
+			}
+			aea.n = (m1*m1 - m2*m2) / (ml2 - ml1)
+			if aea.n == 0 {
+				return aea.paramError("lat_2", ErrInvalidParam)
+			}
+		}
+		aea.ec = 1 - .5*aea.oneEs*math.Log((1-aea.e)/(1+aea.e))/aea.e
+		aea.c = m1*m1 + aea.n*ml1
+		aea.dd = 1 / aea.n
+		aea.rho0 = aea.dd * math.Sqrt(aea.c-aea.n*qsfn(math.Sin(aea.phi0), aea.e, aea.oneEs))
+	} else {
+		if secant {
+			aea.n = .5 * (aea.n + math.Sin(aea.phi2))
+		}
+		aea.n2 = aea.n + aea.n
+		aea.c = cosphi*cosphi + aea.n2*sinphi
+		aea.dd = 1 / aea.n
+		aea.rho0 = aea.dd * math.Sqrt(aea.c-aea.n2*math.Sin(aea.phi0))
+	}
+	return nil
+}
+
+func (aea *AlbersEqualArea) Forward(lng, lat float64) (x, y float64, err error) {
+	return aea.commonFwd(lng, lat, aea.fwd)
+}
+
+func (aea *AlbersEqualArea) Inverse(x, y float64) (lng, lat float64, err error) {
+	return aea.commonInv(x, y, aea.inv)
+}
+
+func (aea *AlbersEqualArea) fwd(lam, phi float64) (x, y float64, err error) {
+	var rho float64
+	if aea.ellips {
+		rho = aea.c - aea.n*qsfn(math.Sin(phi), aea.e, aea.oneEs)
+	} else {
+		rho = aea.c - aea.n2*math.Sin(phi)
+	}
+	if rho < 0 {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	rho = aea.dd * math.Sqrt(rho)
+	lam *= aea.n
+	x = rho * math.Sin(lam)
+	y = aea.rho0 - rho*math.Cos(lam)
+	return x, y, nil
+}
+
+func (aea *AlbersEqualArea) inv(x, y float64) (lng, lat float64, err error) {
+	y = aea.rho0 - y
+	rho := math.Hypot(x, y)
+	if rho == 0 {
+		if aea.n > 0 {
+			return 0, half_pi, nil
+		}
+		return 0, -half_pi, nil
+	}
+	if aea.n < 0 {
+		rho, x, y = -rho, -x, -y
+	}
+	lat = rho / aea.dd
+	if aea.ellips {
+		lat = (aea.c - lat*lat) / aea.n
+		if math.Abs(aea.ec-math.Abs(lat)) > 1.0e-7 {
+			if lat, err = aeaPhi1(lat, aea.e, aea.oneEs); err != nil {
+				return hugeVal, hugeVal, err
+			}
+		} else {
+			lat = math.Copysign(half_pi, lat)
+		}
+	} else if lat = (aea.c - lat*lat) / aea.n2; math.Abs(lat) <= 1 {
+		lat = math.Asin(lat)
+	} else {
+		lat = math.Copysign(half_pi, lat)
+	}
+	lng = math.Atan2(x, y) / aea.n
+	return lng, lat, nil
+}
+
+// aeaPhi1 recovers the latitude from the authalic quantity qs.
+func aeaPhi1(qs, e, oneEs float64) (float64, error) {
+	phi := math.Asin(.5 * qs)
+	if e < 1.0e-7 {
+		return phi, nil
+	}
+	for i := 0; i < 15; i++ {
+		sinpi, cospi := math.Sin(phi), math.Cos(phi)
+		con := e * sinpi
+		com := 1 - con*con
+		dphi := .5 * com * com / cospi * (qs/oneEs - sinpi/com + .5/e*math.Log((1-con)/(1+con)))
+		phi += dphi
+		if math.Abs(dphi) <= 1.0e-10 {
+			return phi, nil
+		}
+	}
+	return math.Inf(-1), noConvergence("aeaPhi1")
+}
+
+// LambertAzimuthalEqualArea implements +proj=laea in its polar, equatorial
+// and oblique aspects.
+type LambertAzimuthalEqualArea struct {
+	*pj
+	mode                  aspect
+	sinb1, cosb1          float64
+	xmf, ymf, mmf, qp, dd float64
+	rq                    float64
+	apa                   [3]float64
+}
+
+func (la *LambertAzimuthalEqualArea) IsLngLat() bool {
+	return false
+}
+
+func (la *LambertAzimuthalEqualArea) init(params paramset) error {
+	if math.Abs(la.phi0) > half_pi+epsln {
+		return la.paramError("lat_0", ErrInvalidParam)
+	}
+	la.mode = aspectOf(la.phi0)
+	if la.es == 0 {
+		if la.mode == oblique {
+			la.sinb1 = math.Sin(la.phi0)
+			la.cosb1 = math.Cos(la.phi0)
+		}
+		return nil
+	}
+	la.qp = qsfn(1, la.e, la.oneEs)
+	la.mmf = .5 / (1 - la.es)
+	la.apa = authset(la.es)
+	switch la.mode {
+	case northPole, southPole:
+		la.dd = 1
+	case equatorial:
+		la.rq = math.Sqrt(.5 * la.qp)
+		la.dd = 1 / la.rq
+		la.xmf = 1
+		la.ymf = .5 * la.qp
+	case oblique:
+		la.rq = math.Sqrt(.5 * la.qp)
+		sinphi := math.Sin(la.phi0)
+		la.sinb1 = qsfn(sinphi, la.e, la.oneEs) / la.qp
+		la.cosb1 = math.Sqrt(1 - la.sinb1*la.sinb1)
+		la.dd = math.Cos(la.phi0) / (math.Sqrt(1-la.es*sinphi*sinphi) * la.rq * la.cosb1)
+		la.xmf = la.rq * la.dd
+		la.ymf = la.rq / la.dd
+	}
+	return nil
+}
+
+func (la *LambertAzimuthalEqualArea) Forward(lng, lat float64) (x, y float64, err error) {
+	return la.commonFwd(lng, lat, la.fwd)
+}
+
+func (la *LambertAzimuthalEqualArea) Inverse(x, y float64) (lng, lat float64, err error) {
+	return la.commonInv(x, y, la.inv)
+}
+
+func (la *LambertAzimuthalEqualArea) fwd(lam, phi float64) (x, y float64, err error) {
+	if la.es == 0 {
+		return la.sphereFwd(lam, phi)
+	}
+	coslam, sinlam := math.Cos(lam), math.Sin(lam)
+	q := qsfn(math.Sin(phi), la.e, la.oneEs)
+	var sinb, cosb, b float64
+	if la.mode == oblique || la.mode == equatorial {
+		sinb = q / la.qp
+		if cosb2 := 1 - sinb*sinb; cosb2 > 0 {
+			cosb = math.Sqrt(cosb2)
+		}
+	}
+	switch la.mode {
+	case oblique:
+		b = 1 + la.sinb1*sinb + la.cosb1*cosb*coslam
+	case equatorial:
+		b = 1 + cosb*coslam
+	case northPole:
+		b = half_pi + phi
+		q = la.qp - q
+	case southPole:
+		b = phi - half_pi
+		q = la.qp + q
+	}
+	if math.Abs(b) < epsln {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	switch la.mode {
+	case oblique:
+		b = math.Sqrt(2 / b)
+		y = la.ymf * b * (la.cosb1*sinb - la.sinb1*cosb*coslam)
+		x = la.xmf * b * cosb * sinlam
+	case equatorial:
+		b = math.Sqrt(2 / (1 + cosb*coslam))
+		y = b * sinb * la.ymf
+		x = la.xmf * b * cosb * sinlam
+	case northPole, southPole:
+		if q >= 1e-15 {
+			b = math.Sqrt(q)
+			x = b * sinlam
+			if la.mode == southPole {
+				y = coslam * b
+			} else {
+				y = coslam * -b
+			}
+		}
+	}
+	return x, y, nil
+}
+
+func (la *LambertAzimuthalEqualArea) sphereFwd(lam, phi float64) (x, y float64, err error) {
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	coslam := math.Cos(lam)
+	switch la.mode {
+	case equatorial, oblique:
+		if la.mode == equatorial {
+			y = 1 + cosphi*coslam
+		} else {
+			y = 1 + la.sinb1*sinphi + la.cosb1*cosphi*coslam
+		}
+		if y <= epsln {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		y = math.Sqrt(2 / y)
+		x = y * cosphi * math.Sin(lam)
+		if la.mode == equatorial {
+			y *= sinphi
+		} else {
+			y *= la.cosb1*sinphi - la.sinb1*cosphi*coslam
+		}
+	case northPole, southPole:
+		if la.mode == northPole {
+			coslam = -coslam
+		}
+		if math.Abs(phi+la.phi0) < epsln {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		y = fort_pi - phi*.5
+		if la.mode == southPole {
+			y = 2 * math.Cos(y)
+		} else {
+			y = 2 * math.Sin(y)
+		}
+		x = y * math.Sin(lam)
+		y *= coslam
+	}
+	return x, y, nil
+}
+
+func (la *LambertAzimuthalEqualArea) inv(x, y float64) (lng, lat float64, err error) {
+	if la.es == 0 {
+		return la.sphereInv(x, y)
+	}
+	var ab float64
+	switch la.mode {
+	case equatorial, oblique:
+		x /= la.dd
+		y *= la.dd
+		rho := math.Hypot(x, y)
+		if rho < epsln {
+			return 0, la.phi0, nil
+		}
+		// past the disc that holds the whole globe
+		if .5*rho/la.rq > 1 {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		sCe := 2 * math.Asin(.5*rho/la.rq)
+		cCe := math.Cos(sCe)
+		sCe = math.Sin(sCe)
+		x *= sCe
+		if la.mode == oblique {
+			ab = cCe*la.sinb1 + y*sCe*la.cosb1/rho
+			y = rho*la.cosb1*cCe - y*la.sinb1*sCe
+		} else {
+			ab = y * sCe / rho
+			y = rho * cCe
+		}
+	case northPole, southPole:
+		if la.mode == northPole {
+			y = -y
+		}
+		q := x*x + y*y
+		if q == 0 {
+			return 0, la.phi0, nil
+		}
+		ab = 1 - q/la.qp
+		if la.mode == southPole {
+			ab = -ab
+		}
+	}
+	if math.Abs(ab) > 1 {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	return math.Atan2(x, y), authlatq(math.Asin(ab), la.apa, la.e, la.oneEs, la.qp), nil
+}
+
+func (la *LambertAzimuthalEqualArea) sphereInv(x, y float64) (lng, lat float64, err error) {
+	rh := math.Hypot(x, y)
+	if lat = rh * .5; lat > 1 {
+		return hugeVal, hugeVal, ErrToleranceCondition
+	}
+	lat = 2 * math.Asin(lat)
+	var sinz, cosz float64
+	if la.mode == oblique || la.mode == equatorial {
+		sinz, cosz = math.Sin(lat), math.Cos(lat)
+	}
+	switch la.mode {
+	case equatorial:
+		if math.Abs(rh) <= epsln {
+			lat = 0
+		} else {
+			lat = math.Asin(y * sinz / rh)
+		}
+		x *= sinz
+		y = cosz * rh
+	case oblique:
+		if math.Abs(rh) <= epsln {
+			lat = la.phi0
+		} else {
+			lat = math.Asin(cosz*la.sinb1 + y*sinz*la.cosb1/rh)
+		}
+		x *= sinz * la.cosb1
+		y = (cosz - math.Sin(lat)*la.sinb1) * rh
+	case northPole:
+		y = -y
+		lat = half_pi - lat
+	case southPole:
+		lat -= half_pi
+	}
+	if y != 0 || (la.mode != equatorial && la.mode != oblique) {
+		lng = math.Atan2(x, y)
+	}
+	return lng, lat, nil
+}
+
+// AzimuthalEquidistant implements +proj=aeqd.  On the ellipsoid the polar
+// aspects use meridional distances and the others solve the geodesic from
+// the centre; +guam selects the approximation used for the Guam grid.
+type AzimuthalEquidistant struct {
+	*pj
+	mode           aspect
+	guam           bool
+	sinph0, cosph0 float64
+	en             [5]float64
+	m1, mp         float64
+	geod           *geodesic
+}
+
+func (ae *AzimuthalEquidistant) IsLngLat() bool {
+	return false
+}
+
+func (ae *AzimuthalEquidistant) init(params paramset) error {
+	ae.mode = aspectOf(ae.phi0)
+	switch ae.mode {
+	case northPole:
+		ae.sinph0, ae.cosph0 = 1, 0
+	case southPole:
+		ae.sinph0, ae.cosph0 = -1, 0
+	case equatorial:
+		ae.sinph0, ae.cosph0 = 0, 1
+	case oblique:
+		ae.sinph0, ae.cosph0 = math.Sin(ae.phi0), math.Cos(ae.phi0)
+	}
+	if ae.es == 0 {
+		return nil
+	}
+	ae.en = enfn(ae.es)
+	if ae.guam, _ = params.bool("guam"); ae.guam {
+		ae.m1 = mlfn(ae.phi0, ae.sinph0, ae.cosph0, ae.en)
+		return nil
+	}
+	switch ae.mode {
+	case northPole:
+		ae.mp = mlfn(half_pi, 1, 0, ae.en)
+	case southPole:
+		ae.mp = mlfn(-half_pi, -1, 0, ae.en)
+	default:
+		ae.geod = newGeodesic(ae.es / (1 + math.Sqrt(1-ae.es)))
+	}
+	return nil
+}
+
+func (ae *AzimuthalEquidistant) Forward(lng, lat float64) (x, y float64, err error) {
+	return ae.commonFwd(lng, lat, ae.fwd)
+}
+
+func (ae *AzimuthalEquidistant) Inverse(x, y float64) (lng, lat float64, err error) {
+	return ae.commonInv(x, y, ae.inv)
+}
+
+func (ae *AzimuthalEquidistant) fwd(lam, phi float64) (x, y float64, err error) {
+	if ae.es == 0 {
+		return ae.sphereFwd(lam, phi)
+	} else if ae.guam {
+		return ae.guamFwd(lam, phi)
+	}
+	coslam := math.Cos(lam)
+	switch ae.mode {
+	case northPole, southPole:
+		if ae.mode == northPole {
+			coslam = -coslam
+		}
+		rho := math.Abs(ae.mp - mlfn(phi, math.Sin(phi), math.Cos(phi), ae.en))
+		return rho * math.Sin(lam), rho * coslam, nil
+	}
+	if math.Abs(lam) < epsln && math.Abs(phi-ae.phi0) < epsln {
+		return 0, 0, nil
+	}
+	s12, azi1, _ := ae.geod.inverse(ae.phi0, 0, phi, lam)
+	return s12 * math.Sin(azi1), s12 * math.Cos(azi1), nil
+}
+
+func (ae *AzimuthalEquidistant) guamFwd(lam, phi float64) (x, y float64, err error) {
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	t := 1 / math.Sqrt(1-ae.es*sinphi*sinphi)
+	x = lam * cosphi * t
+	y = mlfn(phi, sinphi, cosphi, ae.en) - ae.m1 + .5*lam*lam*cosphi*sinphi*t
+	return x, y, nil
+}
+
+func (ae *AzimuthalEquidistant) sphereFwd(lam, phi float64) (x, y float64, err error) {
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	coslam := math.Cos(lam)
+	switch ae.mode {
+	case equatorial, oblique:
+		if ae.mode == equatorial {
+			y = cosphi * coslam
+		} else {
+			y = ae.sinph0*sinphi + ae.cosph0*cosphi*coslam
+		}
+		if math.Abs(math.Abs(y)-1) < 1e-14 {
+			if y < 0 {
+				// the antipode of the centre is a circle, not a point
+				return hugeVal, hugeVal, ErrToleranceCondition
+			}
+			return 0, 0, nil
+		}
+		y = math.Acos(y)
+		y /= math.Sin(y)
+		x = y * cosphi * math.Sin(lam)
+		if ae.mode == equatorial {
+			y *= sinphi
+		} else {
+			y *= ae.cosph0*sinphi - ae.sinph0*cosphi*coslam
+		}
+	case northPole, southPole:
+		if ae.mode == northPole {
+			phi, coslam = -phi, -coslam
+		}
+		if math.Abs(phi-half_pi) < epsln {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		y = half_pi + phi
+		x = y * math.Sin(lam)
+		y *= coslam
+	}
+	return x, y, nil
+}
+
+func (ae *AzimuthalEquidistant) inv(x, y float64) (lng, lat float64, err error) {
+	if ae.es == 0 {
+		return ae.sphereInv(x, y)
+	} else if ae.guam {
+		return ae.guamInv(x, y)
+	}
+	c := math.Hypot(x, y)
+	if c < epsln {
+		return 0, ae.phi0, nil
+	}
+	switch ae.mode {
+	case northPole:
+		lat, err = invMlfn(ae.mp-c, ae.es, ae.en)
+		return math.Atan2(x, -y), lat, err
+	case southPole:
+		lat, err = invMlfn(ae.mp+c, ae.es, ae.en)
+		return math.Atan2(x, y), lat, err
+	}
+	lat, lng, _ = ae.geod.direct(ae.phi0, 0, math.Atan2(x, y), c)
+	return lng, lat, nil
+}
+
+func (ae *AzimuthalEquidistant) guamInv(x, y float64) (lng, lat float64, err error) {
+	x2 := 0.5 * x * x
+	lat = ae.phi0
+	var t float64
+	for i := 0; i < 3; i++ {
+		t = ae.e * math.Sin(lat)
+		t = math.Sqrt(1 - t*t)
+		if lat, err = invMlfn(ae.m1+y-x2*math.Tan(lat)*t, ae.es, ae.en); err != nil {
+			return hugeVal, hugeVal, err
+		}
+	}
+	return x * t / math.Cos(lat), lat, nil
+}
+
+func (ae *AzimuthalEquidistant) sphereInv(x, y float64) (lng, lat float64, err error) {
+	cRh := math.Hypot(x, y)
+	if cRh > math.Pi {
+		if cRh-epsln > math.Pi {
+			return hugeVal, hugeVal, ErrToleranceCondition
+		}
+		cRh = math.Pi
+	} else if cRh < epsln {
+		return 0, ae.phi0, nil
+	}
+	switch ae.mode {
+	case equatorial, oblique:
+		sinc, cosc := math.Sin(cRh), math.Cos(cRh)
+		if ae.mode == equatorial {
+			lat = aasin(y * sinc / cRh)
+			x *= sinc
+			y = cosc * cRh
+		} else {
+			lat = aasin(cosc*ae.sinph0 + y*sinc*ae.cosph0/cRh)
+			y = (cosc - ae.sinph0*math.Sin(lat)) * cRh
+			x *= sinc * ae.cosph0
+		}
+		if y != 0 {
+			lng = math.Atan2(x, y)
+		}
+	case northPole:
+		lat = half_pi - cRh
+		lng = math.Atan2(x, -y)
+	case southPole:
+		lat = cRh - half_pi
+		lng = math.Atan2(x, y)
+	}
+	return lng, lat, nil
+}
+
+// Orthographic implements +proj=ortho, the view of the globe from
+// infinitely far away.  Points on the far side of the globe are not
+// visible and fail with ErrPointNotVisible.
+type Orthographic struct {
+	*pj
+	mode           aspect
+	sinph0, cosph0 float64
+	nu0            float64
+}
+
+func (o *Orthographic) IsLngLat() bool {
+	return false
+}
+
+func (o *Orthographic) init(params paramset) error {
+	o.mode = aspectOf(o.phi0)
+	o.sinph0, o.cosph0 = math.Sin(o.phi0), math.Cos(o.phi0)
+	o.nu0 = 1 / math.Sqrt(1-o.es*o.sinph0*o.sinph0)
+	return nil
+}
+
+func (o *Orthographic) Forward(lng, lat float64) (x, y float64, err error) {
+	return o.commonFwd(lng, lat, o.fwd)
+}
+
+func (o *Orthographic) Inverse(x, y float64) (lng, lat float64, err error) {
+	return o.commonInv(x, y, o.inv)
+}
+
+func (o *Orthographic) fwd(lam, phi float64) (x, y float64, err error) {
+	if o.es == 0 {
+		return o.sphereFwd(lam, phi)
+	}
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	sinlam, coslam := math.Sin(lam), math.Cos(lam)
+	// the normal at the point must face the viewer
+	if o.sinph0*sinphi+o.cosph0*cosphi*coslam < -epsln {
+		return hugeVal, hugeVal, ErrPointNotVisible
+	}
+	nu := 1 / math.Sqrt(1-o.es*sinphi*sinphi)
+	x = nu * cosphi * sinlam
+	y = nu*(sinphi*o.cosph0-cosphi*o.sinph0*coslam) + o.es*(o.nu0*o.sinph0-nu*sinphi)*o.cosph0
+	return x, y, nil
+}
+
+func (o *Orthographic) sphereFwd(lam, phi float64) (x, y float64, err error) {
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	coslam := math.Cos(lam)
+	switch o.mode {
+	case equatorial:
+		if cosphi*coslam < -epsln {
+			return hugeVal, hugeVal, ErrPointNotVisible
+		}
+		y = sinphi
+	case oblique:
+		if o.sinph0*sinphi+o.cosph0*cosphi*coslam < -epsln {
+			return hugeVal, hugeVal, ErrPointNotVisible
+		}
+		y = o.cosph0*sinphi - o.sinph0*cosphi*coslam
+	case northPole, southPole:
+		if o.mode == northPole {
+			coslam = -coslam
+		}
+		if math.Abs(phi-o.phi0)-epsln > half_pi {
+			return hugeVal, hugeVal, ErrPointNotVisible
+		}
+		y = cosphi * coslam
+	}
+	return cosphi * math.Sin(lam), y, nil
+}
+
+func (o *Orthographic) inv(x, y float64) (lng, lat float64, err error) {
+	lng, lat, err = o.sphereInv(x, y)
+	if err != nil || o.es == 0 {
+		return lng, lat, err
+	}
+	// there's no closed form on the ellipsoid, so refine the spherical
+	// solution with Newton's method
+	const h = 1e-8
+	for i := 0; i < 20; i++ {
+		fx, fy, err := o.fwd(lng, lat)
+		if err != nil {
+			return hugeVal, hugeVal, err
+		}
+		dx, dy := x-fx, y-fy
+		if math.Abs(dx) < 1e-15 && math.Abs(dy) < 1e-15 {
+			return lng, lat, nil
+		}
+		xl, yl, err := o.fwd(lng+h, lat)
+		if err != nil {
+			return hugeVal, hugeVal, err
+		}
+		xp, yp, err := o.fwd(lng, lat+h)
+		if err != nil {
+			return hugeVal, hugeVal, err
+		}
+		j11, j12 := (xl-fx)/h, (xp-fx)/h
+		j21, j22 := (yl-fy)/h, (yp-fy)/h
+		det := j11*j22 - j12*j21
+		if det == 0 {
+			break
+		}
+		lng += (j22*dx - j12*dy) / det
+		lat += (j11*dy - j21*dx) / det
+		lat = math.Max(-half_pi, math.Min(half_pi, lat))
+	}
+	return hugeVal, hugeVal, noConvergence("ortho")
+}
+
+func (o *Orthographic) sphereInv(x, y float64) (lng, lat float64, err error) {
+	rh := math.Hypot(x, y)
+	sinc := rh
+	if sinc > 1 {
+		if sinc-1 > epsln {
+			return hugeVal, hugeVal, ErrPointNotVisible
+		}
+		sinc = 1
+	}
+	cosc := math.Sqrt(1 - sinc*sinc)
+	if math.Abs(rh) <= epsln {
+		return 0, o.phi0, nil
+	}
+	switch o.mode {
+	case northPole:
+		y = -y
+		lat = math.Acos(sinc)
+	case southPole:
+		lat = -math.Acos(sinc)
+	case equatorial:
+		lat = aasin(y * sinc / rh)
+		x *= sinc
+		y = cosc * rh
+	case oblique:
+		lat = aasin(cosc*o.sinph0 + y*sinc*o.cosph0/rh)
+		y = (cosc - o.sinph0*math.Sin(lat)) * rh
+		x *= sinc * o.cosph0
+	}
+	if y == 0 && (o.mode == oblique || o.mode == equatorial) {
+		if x != 0 {
+			lng = math.Copysign(half_pi, x)
+		}
+	} else {
+		lng = math.Atan2(x, y)
+	}
+	return lng, lat, nil
+}
+
+// Gnomonic implements +proj=gnom, which projects great circles as straight
+// lines.  It's only defined on the sphere and can show less than a
+// hemisphere; anything on or beyond the horizon fails with
+// ErrPointNotVisible.
+type Gnomonic struct {
+	*pj
+	mode           aspect
+	sinph0, cosph0 float64
+}
+
+func (g *Gnomonic) IsLngLat() bool {
+	return false
+}
+
+func (g *Gnomonic) init(params paramset) error {
+	g.es, g.e = 0, 0
+	g.mode = aspectOf(g.phi0)
+	g.sinph0, g.cosph0 = math.Sin(g.phi0), math.Cos(g.phi0)
+	return nil
+}
+
+func (g *Gnomonic) Forward(lng, lat float64) (x, y float64, err error) {
+	return g.commonFwd(lng, lat, g.fwd)
+}
+
+func (g *Gnomonic) Inverse(x, y float64) (lng, lat float64, err error) {
+	return g.commonInv(x, y, g.inv)
+}
+
+func (g *Gnomonic) fwd(lam, phi float64) (x, y float64, err error) {
+	sinphi, cosphi := math.Sin(phi), math.Cos(phi)
+	coslam := math.Cos(lam)
+	switch g.mode {
+	case equatorial:
+		y = cosphi * coslam
+	case oblique:
+		y = g.sinph0*sinphi + g.cosph0*cosphi*coslam
+	case southPole:
+		y = -sinphi
+	case northPole:
+		y = sinphi
+	}
+	if y <= epsln {
+		return hugeVal, hugeVal, ErrPointNotVisible
+	}
+	y = 1 / y
+	x = y * cosphi * math.Sin(lam)
+	switch g.mode {
+	case equatorial:
+		y *= sinphi
+	case oblique:
+		y *= g.cosph0*sinphi - g.sinph0*cosphi*coslam
+	case northPole:
+		y *= cosphi * -coslam
+	case southPole:
+		y *= cosphi * coslam
+	}
+	return x, y, nil
+}
+
+func (g *Gnomonic) inv(x, y float64) (lng, lat float64, err error) {
+	rh := math.Hypot(x, y)
+	lat = math.Atan(rh)
+	sinz := math.Sin(lat)
+	cosz := math.Sqrt(1 - sinz*sinz)
+	if math.Abs(rh) <= epsln {
+		return 0, g.phi0, nil
+	}
+	switch g.mode {
+	case oblique:
+		lat = aasin(cosz*g.sinph0 + y*sinz*g.cosph0/rh)
+		y = (cosz - g.sinph0*math.Sin(lat)) * rh
+		x *= sinz * g.cosph0
+	case equatorial:
+		lat = aasin(y * sinz / rh)
+		y = cosz * rh
+		x *= sinz
+	case southPole:
+		lat -= half_pi
+	case northPole:
+		lat = half_pi - lat
+		y = -y
+	}
+	return math.Atan2(x, y), lat, nil
+}
@@ -0,0 +1,485 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+const projjsonSchema = "https://proj.org/schemas/v0.5/projjson.schema.json"
+
+// projjsonCRS is the part of the PROJJSON schema we understand: bound,
+// projected, geographic and geodetic CRSs.
+type projjsonCRS struct {
+	Schema string `json:"$schema,omitempty"`
+	Type   string `json:"type"`
+	Name   string `json:"name,omitempty"`
+
+	SourceCRS      *projjsonCRS       `json:"source_crs,omitempty"`
+	TargetCRS      *projjsonCRS       `json:"target_crs,omitempty"`
+	Transformation *projjsonOperation `json:"transformation,omitempty"`
+
+	BaseCRS    *projjsonCRS       `json:"base_crs,omitempty"`
+	Conversion *projjsonOperation `json:"conversion,omitempty"`
+
+	Datum            *projjsonDatum `json:"datum,omitempty"`
+	DatumEnsemble    *projjsonDatum `json:"datum_ensemble,omitempty"`
+	CoordinateSystem *projjsonCS    `json:"coordinate_system,omitempty"`
+	projjsonIdentified
+}
+
+type projjsonDatum struct {
+	Type          string                 `json:"type,omitempty"`
+	Name          string                 `json:"name"`
+	Ellipsoid     *projjsonEllipsoid     `json:"ellipsoid,omitempty"`
+	PrimeMeridian *projjsonPrimeMeridian `json:"prime_meridian,omitempty"`
+}
+
+type projjsonEllipsoid struct {
+	Name              string         `json:"name"`
+	SemiMajorAxis     *projjsonValue `json:"semi_major_axis,omitempty"`
+	SemiMinorAxis     *projjsonValue `json:"semi_minor_axis,omitempty"`
+	InverseFlattening float64        `json:"inverse_flattening,omitempty"`
+	Radius            *projjsonValue `json:"radius,omitempty"`
+}
+
+type projjsonPrimeMeridian struct {
+	Name      string         `json:"name"`
+	Longitude *projjsonValue `json:"longitude,omitempty"`
+}
+
+type projjsonCS struct {
+	Subtype string         `json:"subtype"`
+	Axis    []projjsonAxis `json:"axis"`
+}
+
+type projjsonAxis struct {
+	Name         string        `json:"name"`
+	Abbreviation string        `json:"abbreviation"`
+	Direction    string        `json:"direction"`
+	Unit         *projjsonUnit `json:"unit,omitempty"`
+}
+
+// projjsonOperation is a conversion or a transformation.
+type projjsonOperation struct {
+	Name       string              `json:"name"`
+	Method     projjsonMethod      `json:"method"`
+	Parameters []projjsonParameter `json:"parameters,omitempty"`
+}
+
+type projjsonMethod struct {
+	Name string `json:"name"`
+	projjsonIdentified
+}
+
+type projjsonParameter struct {
+	Name string `json:"name"`
+	// Value is a number, or a file name for grid shifts
+	Value interface{}   `json:"value"`
+	Unit  *projjsonUnit `json:"unit,omitempty"`
+	projjsonIdentified
+}
+
+type projjsonIdentified struct {
+	ID  *projjsonID  `json:"id,omitempty"`
+	IDs []projjsonID `json:"ids,omitempty"`
+}
+
+type projjsonID struct {
+	Authority string `json:"authority"`
+	// Code is a number or a string
+	Code interface{} `json:"code"`
+}
+
+// projjsonUnit is written as just its name if it's one of projjsonUnits,
+// otherwise as an object with its type and conversion factor.
+type projjsonUnit struct {
+	Type             string  `json:"type"`
+	Name             string  `json:"name"`
+	ConversionFactor float64 `json:"conversion_factor,omitempty"`
+}
+
+var projjsonUnits = map[string]float64{"metre": 1, "degree": d2r, "unity": 1}
+
+func (u *projjsonUnit) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		f, ok := projjsonUnits[name]
+		if !ok {
+			return ErrInvalidPROJJSON
+		}
+		*u = projjsonUnit{Name: name, ConversionFactor: f}
+		return nil
+	}
+	type plain projjsonUnit
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+func (u projjsonUnit) MarshalJSON() ([]byte, error) {
+	if u.Type == "" {
+		return json.Marshal(u.Name)
+	}
+	type plain projjsonUnit
+	return json.Marshal(plain(u))
+}
+
+// projjsonValue is a number in the default unit, or an object with a value
+// and unit.
+type projjsonValue struct {
+	Value float64       `json:"value"`
+	Unit  *projjsonUnit `json:"unit,omitempty"`
+}
+
+func (v *projjsonValue) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &v.Value); err == nil {
+		return nil
+	}
+	type plain projjsonValue
+	return json.Unmarshal(data, (*plain)(v))
+}
+
+func (v projjsonValue) MarshalJSON() ([]byte, error) {
+	if v.Unit == nil {
+		return json.Marshal(v.Value)
+	}
+	type plain projjsonValue
+	return json.Marshal(plain(v))
+}
+
+// NewProjectionFromPROJJSON sets up a projection from a PROJJSON document,
+// as written by PROJ 6.2 and later (schema v0.5 onwards).
+func NewProjectionFromPROJJSON(data []byte) (Projection, error) {
+	var crs projjsonCRS
+	if err := json.Unmarshal(data, &crs); err != nil {
+		return nil, ErrInvalidPROJJSON
+	}
+	// PROJJSON is WKT2 in another syntax
+	parms, err := wkt2Params(crs.wkt())
+	if err == ErrInvalidWKT {
+		return nil, ErrInvalidPROJJSON
+	} else if err != nil {
+		return nil, err
+	}
+	return newProjection(parms)
+}
+
+// wkt maps the document onto the WKT2 nodes it's equivalent to.
+func (c *projjsonCRS) wkt() *wktNode {
+	n := &wktNode{values: []string{c.Name}}
+	switch c.Type {
+	case "BoundCRS":
+		n.keyword = "BOUNDCRS"
+		if c.SourceCRS != nil {
+			n.children = append(n.children, &wktNode{keyword: "SOURCECRS", children: []*wktNode{c.SourceCRS.wkt()}})
+		}
+		if c.Transformation != nil {
+			n.children = append(n.children, c.Transformation.wkt("ABRIDGEDTRANSFORMATION"))
+		}
+		return n
+	case "ProjectedCRS":
+		n.keyword = "PROJCRS"
+		if c.BaseCRS != nil {
+			// the type of the base is implied
+			base := *c.BaseCRS
+			if base.Type == "" {
+				base.Type = "GeographicCRS"
+			}
+			node := base.wkt()
+			node.keyword = "BASE" + node.keyword
+			n.children = append(n.children, node)
+		}
+		if c.Conversion != nil {
+			n.children = append(n.children, c.Conversion.wkt("CONVERSION"))
+		}
+		n.children = append(n.children, c.CoordinateSystem.wkt()...)
+		return n
+	case "GeographicCRS":
+		n.keyword = "GEOGCRS"
+	case "GeodeticCRS":
+		n.keyword = "GEODCRS"
+	default:
+		// which wkt2Params won't recognise
+		n.keyword = c.Type
+		return n
+	}
+
+	datum, keyword := c.Datum, "DATUM"
+	if datum == nil {
+		datum, keyword = c.DatumEnsemble, "ENSEMBLE"
+	}
+	if datum != nil {
+		n.children = append(n.children, datum.wkt(keyword))
+		if pm := datum.PrimeMeridian; pm != nil && pm.Longitude != nil {
+			unit := pm.Longitude.Unit
+			if unit == nil {
+				unit = &projjsonUnit{Name: "degree", ConversionFactor: d2r}
+			}
+			n.children = append(n.children, &wktNode{
+				keyword:  "PRIMEM",
+				values:   []string{pm.Name, formatFloat(pm.Longitude.Value)},
+				children: unit.wkt(),
+			})
+		}
+	}
+	n.children = append(n.children, c.CoordinateSystem.wkt()...)
+	return n
+}
+
+func (d *projjsonDatum) wkt(keyword string) *wktNode {
+	n := &wktNode{keyword: keyword, values: []string{d.Name}}
+	e := d.Ellipsoid
+	if e == nil {
+		return n
+	}
+	var a, rf float64
+	switch {
+	case e.Radius != nil:
+		a = e.Radius.in(1)
+	case e.SemiMajorAxis != nil:
+		a, rf = e.SemiMajorAxis.in(1), e.InverseFlattening
+		if e.SemiMinorAxis != nil {
+			if b := e.SemiMinorAxis.in(1); b != a {
+				rf = a / (a - b)
+			}
+		}
+	}
+	n.children = append(n.children, &wktNode{keyword: "ELLIPSOID", values: []string{e.Name, formatFloat(a), formatFloat(rf)}})
+	return n
+}
+
+// in returns the value converted by its unit, or def.
+func (v *projjsonValue) in(def float64) float64 {
+	if v.Unit != nil && v.Unit.ConversionFactor > 0 {
+		return v.Value * v.Unit.ConversionFactor
+	}
+	return v.Value * def
+}
+
+func (cs *projjsonCS) wkt() []*wktNode {
+	if cs == nil {
+		return nil
+	}
+	nodes := []*wktNode{{keyword: "CS", values: []string{cs.Subtype}}}
+	for i, a := range cs.Axis {
+		order := &wktNode{keyword: "ORDER", values: []string{strconv.Itoa(i + 1)}}
+		nodes = append(nodes, &wktNode{
+			keyword:  "AXIS",
+			values:   []string{a.Name, a.Direction},
+			children: append([]*wktNode{order}, a.Unit.wkt()...),
+		})
+	}
+	return nodes
+}
+
+func (o *projjsonOperation) wkt(keyword string) *wktNode {
+	method := &wktNode{keyword: "METHOD", values: []string{o.Method.Name}, children: o.Method.wkt()}
+	n := &wktNode{keyword: keyword, values: []string{o.Name}, children: []*wktNode{method}}
+	for _, p := range o.Parameters {
+		switch v := p.Value.(type) {
+		case string:
+			n.children = append(n.children, &wktNode{keyword: "PARAMETERFILE", values: []string{p.Name, v}})
+		case float64:
+			n.children = append(n.children, &wktNode{
+				keyword:  "PARAMETER",
+				values:   []string{p.Name, formatFloat(v)},
+				children: append(p.Unit.wkt(), p.wkt()...),
+			})
+		}
+	}
+	return n
+}
+
+func (u *projjsonUnit) wkt() []*wktNode {
+	if u == nil {
+		return nil
+	}
+	return []*wktNode{{keyword: "UNIT", values: []string{u.Name, formatFloat(u.ConversionFactor)}}}
+}
+
+// wkt returns the first ID, if there is one.
+func (i projjsonIdentified) wkt() []*wktNode {
+	id := i.ID
+	if id == nil && len(i.IDs) > 0 {
+		id = &i.IDs[0]
+	}
+	if id == nil {
+		return nil
+	}
+	var code string
+	switch c := id.Code.(type) {
+	case string:
+		code = c
+	case float64:
+		code = formatFloat(c)
+	}
+	return []*wktNode{{keyword: "ID", values: []string{id.Authority, code}}}
+}
+
+// FormatPROJJSON describes a Projection made by NewProjection as a PROJJSON
+// document.  Like FormatWKT2, datum shifts are written as a BoundCRS to
+// WGS84 and vertical grids are left out.
+func FormatPROJJSON(proj Projection) ([]byte, error) {
+	imp, ok := proj.(impl)
+	if !ok {
+		return nil, ErrUnsupportedProj
+	}
+	p := imp.base()
+	degree := &projjsonUnit{Name: "degree", ConversionFactor: d2r}
+	length := &projjsonUnit{Name: "metre", ConversionFactor: 1}
+	if p.to_meter != 1 {
+		length = &projjsonUnit{Type: "LinearUnit", Name: unitName(p.to_meter), ConversionFactor: p.to_meter}
+	}
+
+	var crs *projjsonCRS
+	switch {
+	case p.proj == "geocent":
+		crs = &projjsonCRS{Type: "GeodeticCRS", Name: "unknown", Datum: p.projjsonDatum(),
+			CoordinateSystem: &projjsonCS{Subtype: "Cartesian", Axis: []projjsonAxis{
+				{"Geocentric X", "X", "geocentricX", length},
+				{"Geocentric Y", "Y", "geocentricY", length},
+				{"Geocentric Z", "Z", "geocentricZ", length},
+			}}}
+	case proj.IsLngLat():
+		crs = &projjsonCRS{Type: "GeographicCRS", Name: "unknown", Datum: p.projjsonDatum(),
+			CoordinateSystem: &projjsonCS{Subtype: "ellipsoidal", Axis: p.projjsonAxes(true, degree)}}
+	default:
+		m, values, err := p.conversionMethod()
+		if err != nil {
+			return nil, err
+		}
+		crs = &projjsonCRS{Type: "ProjectedCRS", Name: "unknown",
+			BaseCRS:          &projjsonCRS{Type: "GeographicCRS", Name: "unknown", Datum: p.projjsonDatum()},
+			Conversion:       p.projjsonConversion(m, values),
+			CoordinateSystem: &projjsonCS{Subtype: "Cartesian", Axis: p.projjsonAxes(false, length)}}
+	}
+
+	if shift := p.projjsonShift(); shift != nil {
+		wgs84 := &projjsonCRS{Type: "GeographicCRS", Name: "WGS 84",
+			Datum: &projjsonDatum{Type: "GeodeticReferenceFrame", Name: "World Geodetic System 1984",
+				Ellipsoid: &projjsonEllipsoid{Name: "WGS 84", SemiMajorAxis: &projjsonValue{Value: 6378137}, InverseFlattening: 298.257223563}},
+			CoordinateSystem: &projjsonCS{Subtype: "ellipsoidal", Axis: []projjsonAxis{
+				{"Geodetic latitude", "Lat", "north", degree},
+				{"Geodetic longitude", "Lon", "east", degree},
+			}},
+			projjsonIdentified: projjsonIdentified{ID: &projjsonID{"EPSG", 4326}}}
+		crs = &projjsonCRS{Type: "BoundCRS", SourceCRS: crs, TargetCRS: wgs84, Transformation: shift}
+	}
+	crs.Schema = projjsonSchema
+	return json.MarshalIndent(crs, "", "  ")
+}
+
+// projjsonDatum writes the datum, its ellipsoid and prime meridian.
+func (p *pj) projjsonDatum() *projjsonDatum {
+	name, ellps := p.datumNames()
+	e := &projjsonEllipsoid{Name: ellps}
+	if rf := p.rf(); rf == 0 {
+		e.Radius = &projjsonValue{Value: p.aOrig}
+	} else {
+		e.SemiMajorAxis = &projjsonValue{Value: p.aOrig}
+		e.InverseFlattening = projjsonNumber(rf)
+	}
+	d := &projjsonDatum{Type: "GeodeticReferenceFrame", Name: name, Ellipsoid: e}
+	if p.from_greenwich != 0 {
+		d.PrimeMeridian = &projjsonPrimeMeridian{Name: p.pmName(), Longitude: &projjsonValue{Value: projjsonNumber(p.from_greenwich / d2r)}}
+	}
+	return d
+}
+
+// projjsonAxes writes the first two axes from +axis.
+func (p *pj) projjsonAxes(geographic bool, unit *projjsonUnit) []projjsonAxis {
+	var axes []projjsonAxis
+	for _, dir := range p.axis[:2] {
+		var a projjsonAxis
+		switch dir {
+		case 'e', 'w':
+			a = projjsonAxis{"Easting", "E", map[rune]string{'e': "east", 'w': "west"}[dir], unit}
+			if geographic {
+				a.Name, a.Abbreviation = "Longitude", "lon"
+			}
+		case 'n', 's':
+			a = projjsonAxis{"Northing", "N", map[rune]string{'n': "north", 's': "south"}[dir], unit}
+			if geographic {
+				a.Name, a.Abbreviation = "Latitude", "lat"
+			}
+		}
+		axes = append(axes, a)
+	}
+	return axes
+}
+
+// projjsonConversion writes the projection method and its parameters.
+func (p *pj) projjsonConversion(m *wkt2Method, values map[string]float64) *projjsonOperation {
+	op := &projjsonOperation{Name: p.conversionName(), Method: projjsonMethod{Name: m.name}}
+	if m.code != "" {
+		op.Method.ID = projjsonEPSG(m.code)
+	}
+	units := map[wkt2Kind]*projjsonUnit{
+		wkt2Angle:  {Name: "degree", ConversionFactor: d2r},
+		wkt2Length: {Name: "metre", ConversionFactor: 1},
+		wkt2Scale:  {Name: "unity", ConversionFactor: 1},
+	}
+	for _, code := range m.params {
+		param := wkt2ParamByCode(code)
+		v := values[code]
+		if param.kind == wkt2Angle {
+			v /= d2r
+		}
+		op.Parameters = append(op.Parameters, projjsonParameter{
+			Name:               param.name,
+			Value:              projjsonNumber(v),
+			Unit:               units[param.kind],
+			projjsonIdentified: projjsonIdentified{ID: projjsonEPSG(code)},
+		})
+	}
+	return op
+}
+
+// projjsonShift writes the transformation to WGS84, if there is one.
+func (p *pj) projjsonShift() *projjsonOperation {
+	const name = "Transformation to WGS84"
+	if p.datumType == PJD_GRIDSHIFT && p.nadgrids != "" {
+		return &projjsonOperation{Name: name,
+			Method: projjsonMethod{"NTv2", projjsonIdentified{ID: projjsonEPSG("9615")}},
+			Parameters: []projjsonParameter{{
+				Name:               "Latitude and longitude difference file",
+				Value:              p.nadgrids,
+				projjsonIdentified: projjsonIdentified{ID: projjsonEPSG("8656")},
+			}}}
+	}
+	values := p.towgs84()
+	if values == nil {
+		return nil
+	}
+
+	methodName, code := helmertMethod(values)
+	op := &projjsonOperation{Name: name, Method: projjsonMethod{methodName, projjsonIdentified{ID: projjsonEPSG(code)}}}
+	units := map[wkt2Kind]*projjsonUnit{
+		wkt2Length: {Name: "metre", ConversionFactor: 1},
+		wkt2Angle:  {Type: "AngularUnit", Name: "arc-second", ConversionFactor: sec2rad},
+		wkt2Scale:  {Type: "ScaleUnit", Name: "parts per million", ConversionFactor: 1e-6},
+	}
+	for i, v := range values {
+		param := wkt2Shifts[i]
+		op.Parameters = append(op.Parameters, projjsonParameter{
+			Name:               param.name,
+			Value:              v,
+			Unit:               units[param.kind],
+			projjsonIdentified: projjsonIdentified{ID: projjsonEPSG(param.code)},
+		})
+	}
+	return op
+}
+
+func projjsonEPSG(code string) *projjsonID {
+	n, _ := strconv.Atoi(code)
+	return &projjsonID{"EPSG", n}
+}
+
+// projjsonNumber rounds away the noise from converting radians to degrees.
+func projjsonNumber(f float64) float64 {
+	f, _ = strconv.ParseFloat(wktNumber(f), 64)
+	return f
+}
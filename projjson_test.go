@@ -0,0 +1,213 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"strings"
+	"testing"
+)
+
+// as written by projinfo -o PROJJSON
+const utm33JSON = `{
+  "$schema": "https://proj.org/schemas/v0.7/projjson.schema.json",
+  "type": "ProjectedCRS",
+  "name": "WGS 84 / UTM zone 33N",
+  "base_crs": {
+    "name": "WGS 84",
+    "datum_ensemble": {
+      "name": "World Geodetic System 1984 ensemble",
+      "members": [{"name": "World Geodetic System 1984 (Transit)"}],
+      "ellipsoid": {
+        "name": "WGS 84",
+        "semi_major_axis": 6378137,
+        "inverse_flattening": 298.257223563
+      },
+      "accuracy": "2.0",
+      "id": {"authority": "EPSG", "code": 6326}
+    },
+    "coordinate_system": {
+      "subtype": "ellipsoidal",
+      "axis": [
+        {"name": "Geodetic latitude", "abbreviation": "Lat", "direction": "north", "unit": "degree"},
+        {"name": "Geodetic longitude", "abbreviation": "Lon", "direction": "east", "unit": "degree"}
+      ]
+    },
+    "id": {"authority": "EPSG", "code": 4326}
+  },
+  "conversion": {
+    "name": "UTM zone 33N",
+    "method": {
+      "name": "Transverse Mercator",
+      "id": {"authority": "EPSG", "code": 9807}
+    },
+    "parameters": [
+      {"name": "Latitude of natural origin", "value": 0, "unit": "degree", "id": {"authority": "EPSG", "code": 8801}},
+      {"name": "Longitude of natural origin", "value": 15, "unit": "degree", "id": {"authority": "EPSG", "code": 8802}},
+      {"name": "Scale factor at natural origin", "value": 0.9996, "unit": "unity", "id": {"authority": "EPSG", "code": 8805}},
+      {"name": "False easting", "value": 500000, "unit": "metre", "id": {"authority": "EPSG", "code": 8806}},
+      {"name": "False northing", "value": 0, "unit": "metre", "id": {"authority": "EPSG", "code": 8807}}
+    ]
+  },
+  "coordinate_system": {
+    "subtype": "Cartesian",
+    "axis": [
+      {"name": "Easting", "abbreviation": "E", "direction": "east", "unit": "metre"},
+      {"name": "Northing", "abbreviation": "N", "direction": "north", "unit": "metre"}
+    ]
+  },
+  "id": {"authority": "EPSG", "code": 32633}
+}`
+
+const osgbJSON = `{
+  "type": "BoundCRS",
+  "source_crs": {
+    "type": "GeographicCRS",
+    "name": "OSGB36",
+    "datum": {
+      "type": "GeodeticReferenceFrame",
+      "name": "Ordnance Survey of Great Britain 1936",
+      "ellipsoid": {
+        "name": "Airy 1830",
+        "semi_major_axis": 6377563.396,
+        "semi_minor_axis": {"value": 6356256.91, "unit": "metre"}
+      },
+      "prime_meridian": {"name": "Greenwich", "longitude": 0}
+    },
+    "coordinate_system": {
+      "subtype": "ellipsoidal",
+      "axis": [
+        {"name": "Longitude", "abbreviation": "lon", "direction": "east", "unit": "degree"},
+        {"name": "Latitude", "abbreviation": "lat", "direction": "north", "unit": "degree"}
+      ]
+    }
+  },
+  "target_crs": {
+    "type": "GeographicCRS",
+    "name": "WGS 84",
+    "datum": {
+      "type": "GeodeticReferenceFrame",
+      "name": "World Geodetic System 1984",
+      "ellipsoid": {"name": "WGS 84", "semi_major_axis": 6378137, "inverse_flattening": 298.257223563}
+    }
+  },
+  "transformation": {
+    "name": "OSGB36 to WGS 84 (6)",
+    "method": {"name": "Position Vector transformation (geog2D domain)", "ids": [{"authority": "EPSG", "code": "9606"}]},
+    "parameters": [
+      {"name": "X-axis translation", "value": 446.448, "unit": "metre", "id": {"authority": "EPSG", "code": 8605}},
+      {"name": "Y-axis translation", "value": -125.157, "unit": "metre", "id": {"authority": "EPSG", "code": 8606}},
+      {"name": "Z-axis translation", "value": 542.06, "unit": "metre", "id": {"authority": "EPSG", "code": 8607}},
+      {"name": "X-axis rotation", "value": 0.15, "unit": {"type": "AngularUnit", "name": "arc-second", "conversion_factor": 4.84813681109536e-06}, "id": {"authority": "EPSG", "code": 8608}},
+      {"name": "Y-axis rotation", "value": 0.247, "unit": {"type": "AngularUnit", "name": "arc-second", "conversion_factor": 4.84813681109536e-06}, "id": {"authority": "EPSG", "code": 8609}},
+      {"name": "Z-axis rotation", "value": 0.842, "unit": {"type": "AngularUnit", "name": "arc-second", "conversion_factor": 4.84813681109536e-06}, "id": {"authority": "EPSG", "code": 8610}},
+      {"name": "Scale difference", "value": -20.489, "unit": {"type": "ScaleUnit", "name": "parts per million", "conversion_factor": 1e-06}, "id": {"authority": "EPSG", "code": 8611}}
+    ]
+  }
+}`
+
+func TestNewProjectionFromPROJJSON(t *testing.T) {
+	for _, test := range []struct {
+		json, proj4 string
+	}{
+		{utm33JSON, "+proj=tmerc +lon_0=15 +k=0.9996 +x_0=500000 +datum=WGS84"},
+		{osgbJSON, "+proj=longlat +ellps=airy +towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489"},
+		{`{"type": "GeographicCRS", "name": "NAD27",
+			"datum": {"name": "North American Datum 1927",
+				"ellipsoid": {"name": "Clarke 1866", "semi_major_axis": 6378206.4, "semi_minor_axis": 6356583.8}}}`,
+			"+proj=longlat +datum=NAD27"},
+		{`{"type": "GeodeticCRS", "name": "sphere",
+			"datum": {"name": "unknown", "ellipsoid": {"name": "sphere", "radius": {"value": 6371, "unit": {"type": "LinearUnit", "name": "kilometre", "conversion_factor": 1000}}}},
+			"coordinate_system": {"subtype": "Cartesian", "axis": []}}`,
+			"+proj=geocent +R=6371000"},
+	} {
+		exp, err := NewProjection(test.proj4)
+		if err != nil {
+			t.Fatalf("%s: %v", test.proj4, err)
+		}
+		proj, err := NewProjectionFromPROJJSON([]byte(test.json))
+		if err != nil {
+			t.Errorf("%s: %v", test.proj4, err)
+			continue
+		}
+		if definition(proj) != definition(exp) {
+			t.Errorf("%s: got %s", test.proj4, definition(proj))
+		}
+	}
+
+	for _, test := range []struct {
+		json string
+		err  error
+	}{
+		{`PROJCRS["unknown"]`, ErrInvalidPROJJSON},
+		{`{"type": "GeographicCRS", "name": "no datum"}`, ErrInvalidPROJJSON},
+		{`{"type": "GeographicCRS", "name": "bad unit", "datum": {"name": "x", "ellipsoid": {"name": "x", "radius": {"value": 1, "unit": "foot"}}}}`, ErrInvalidPROJJSON},
+		{`{"type": "VerticalCRS", "name": "EGM96 height"}`, ErrUnsupportedProj},
+	} {
+		if _, err := NewProjectionFromPROJJSON([]byte(test.json)); err != test.err {
+			t.Errorf("%s: expected %v, got %v", test.json, test.err, err)
+		}
+	}
+}
+
+func TestFormatPROJJSON(t *testing.T) {
+	for _, test := range []struct {
+		proj4    string
+		contains []string
+		// back is what it reads back as, if that's not proj4
+		back string
+	}{
+		{"+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +datum=OSGB36",
+			[]string{`"type": "BoundCRS"`, `"name": "Ordnance Survey of Great Britain 1936"`, `"name": "Airy 1830"`,
+				`"name": "Position Vector transformation (geog2D domain)"`, `"value": 0.9996012717`}, ""},
+		{"+proj=utm +zone=33 +south +datum=WGS84",
+			[]string{`"name": "UTM zone 33S"`, `"name": "World Geodetic System 1984"`, `"unit": "metre"`},
+			"+proj=tmerc +lon_0=15 +k=0.9996 +x_0=500000 +y_0=10000000 +datum=WGS84"},
+		{"+proj=merc +R=6378137 +nadgrids=@null",
+			[]string{`"radius": 6378137`, `"value": "@null"`}, ""},
+		{"+proj=lcc +lat_1=33 +lat_2=45 +lat_0=23 +lon_0=-96 +datum=NAD83",
+			[]string{`"name": "Lambert Conic Conformal (2SP)"`, `"code": 9802`}, ""},
+		{"+proj=stere +lat_0=90 +lat_ts=70 +lon_0=-45 +datum=WGS84",
+			[]string{`"name": "Polar Stereographic (variant B)"`}, ""},
+		{"+proj=sterea +lat_0=52.15616055555555 +lon_0=5.38763888888889 +k=0.9999079 +x_0=155000 +y_0=463000 +ellps=bessel", nil, ""},
+		{"+proj=aea +lat_1=29.5 +lat_2=45.5 +lat_0=37.5 +lon_0=-96 +datum=NAD83", nil, ""},
+		{"+proj=laea +lat_0=52 +lon_0=10 +x_0=4321000 +y_0=3210000 +ellps=GRS80", nil, ""},
+		{"+proj=aeqd +lat_0=40 +lon_0=-100 +ellps=WGS84", nil, ""},
+		{"+proj=gnom +lat_0=40 +lon_0=-100 +R=6371000", nil, ""},
+		{"+proj=eqc +lat_ts=30 +lon_0=10 +ellps=WGS84", nil, ""},
+		{"+proj=longlat +datum=NAD27",
+			[]string{`"subtype": "ellipsoidal"`, `"name": "Clarke 1866"`, `"name": "NTv2"`}, ""},
+		{"+proj=geocent +datum=WGS84",
+			[]string{`"type": "GeodeticCRS"`, `"direction": "geocentricZ"`}, ""},
+	} {
+		proj, err := NewProjection(test.proj4)
+		if err != nil {
+			t.Fatalf("%s: %v", test.proj4, err)
+		}
+		data, err := FormatPROJJSON(proj)
+		if err != nil {
+			t.Errorf("%s: %v", test.proj4, err)
+			continue
+		}
+		for _, s := range append(test.contains, projjsonSchema) {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("%s: expected %s in\n%s", test.proj4, s, data)
+			}
+		}
+
+		// and it should read back as the same thing
+		back, err := NewProjectionFromPROJJSON(data)
+		if err != nil {
+			t.Errorf("%s: %v\n%s", test.proj4, err, data)
+			continue
+		}
+		exp := proj
+		if test.back != "" {
+			exp, _ = NewProjection(test.back)
+		}
+		if definition(back) != definition(exp) {
+			t.Errorf("%s: round trip gave %s, want %s", test.proj4, definition(back), definition(exp))
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+const (
+	wgs84A  = 6378137.0
+	wgs84Es = 0.0066943799901413165
+)
+
+// Transform reprojects the points in x, y and z from src to dst in place,
+// shifting between their datums through WGS84 when both define one.  This
+// is the equivalent of PROJ's pj_transform.  Geographic coordinates are in
+// radians, and all coordinates are in the order and direction of +axis.
+// z holds ellipsoidal heights, or orthometric ones for a projection with
+// +geoidgrids, and may be nil, in which case they're taken to be 0, unless
+// src or dst is geocentric.
+func Transform(src, dst Projection, x, y, z []float64) error {
+	s, ok := src.(impl)
+	if !ok {
+		return argError("Transform", "src", ErrUnsupportedProj)
+	}
+	d, ok := dst.(impl)
+	if !ok {
+		return argError("Transform", "dst", ErrUnsupportedProj)
+	}
+	sp, dp := s.base(), d.base()
+	if err := checkLengths("Transform", x, y, z); err != nil {
+		return err
+	}
+	_, srcGeocent := src.(*Geocentric)
+	_, dstGeocent := dst.(*Geocentric)
+	if z == nil {
+		if srcGeocent || dstGeocent {
+			return argError("Transform", "z", ErrGeocentric)
+		}
+		z = make([]float64, len(x))
+	}
+
+	var err error
+	for i := range x {
+		if x[i] == hugeVal {
+			continue
+		}
+		if sp.axis[2] == 'd' {
+			z[i] = -z[i]
+		}
+		if srcGeocent {
+			gx, gy := sp.fromAxis(x[i], y[i])
+			x[i], y[i], z[i] = sp.FromGeocentric(gx*sp.to_meter, gy*sp.to_meter, z[i]*sp.to_meter)
+		} else if !src.IsLngLat() {
+			if x[i], y[i], err = src.Inverse(x[i], y[i]); err != nil {
+				return err
+			}
+		} else {
+			// Inverse would do this, if we called it
+			x[i], y[i] = sp.fromAxis(x[i], y[i])
+			x[i] += sp.from_greenwich
+		}
+	}
+
+	if !srcGeocent {
+		if err = sp.applyGeoid(false, x, y, z); err != nil {
+			return err
+		}
+	}
+	if err = datumTransform(sp, dp, x, y, z); err != nil {
+		return err
+	}
+	if !dstGeocent {
+		if err = dp.applyGeoid(true, x, y, z); err != nil {
+			return err
+		}
+	}
+
+	for i := range x {
+		if x[i] == hugeVal {
+			continue
+		}
+		if dstGeocent {
+			if x[i], y[i], z[i], err = dp.ToGeocentric(x[i], y[i], z[i]); err != nil {
+				return err
+			}
+			x[i], y[i] = dp.toAxis(x[i]*dp.fr_meter, y[i]*dp.fr_meter)
+			z[i] *= dp.fr_meter
+		} else if !dst.IsLngLat() {
+			if x[i], y[i], err = dst.Forward(x[i], y[i]); err != nil {
+				return err
+			}
+		} else {
+			x[i], y[i] = dp.toAxis(dp.wrapLng(x[i]-dp.from_greenwich), y[i])
+		}
+		if dp.axis[2] == 'd' {
+			z[i] = -z[i]
+		}
+	}
+	return nil
+}
+
+// checkLengths says which of y and z, if either, isn't as long as x.
+func checkLengths(fn string, x, y, z []float64) error {
+	if len(y) != len(x) {
+		return argError(fn, "y", ErrInvalidParam)
+	}
+	if z != nil && len(z) != len(x) {
+		return argError(fn, "z", ErrInvalidParam)
+	}
+	return nil
+}
+
+// minShard is the fewest points TransformParallel gives a worker, below
+// which starting one costs more than it saves.
+const minShard = 4096
+
+// TransformParallel is Transform with the points split between workers
+// goroutines, or GOMAXPROCS if workers isn't positive.  Like Transform,
+// it stops at the first error, which is the error from the earliest
+// points that failed, although other workers may have transformed points
+// after them by then.
+func TransformParallel(src, dst Projection, x, y, z []float64, workers int) error {
+	if err := checkLengths("TransformParallel", x, y, z); err != nil {
+		return err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if max := (len(x) + minShard - 1) / minShard; workers > max {
+		workers = max
+	}
+	if workers <= 1 {
+		return Transform(src, dst, x, y, z)
+	}
+
+	shard := (len(x) + workers - 1) / workers
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo, hi := w*shard, (w+1)*shard
+		if lo >= len(x) {
+			break
+		}
+		if hi > len(x) {
+			hi = len(x)
+		}
+		var zs []float64
+		if z != nil {
+			zs = z[lo:hi]
+		}
+		wg.Add(1)
+		go func(w int, xs, ys, zs []float64) {
+			defer wg.Done()
+			errs[w] = Transform(src, dst, xs, ys, zs)
+		}(w, x[lo:hi], y[lo:hi], zs)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareDatums reports whether src and dst share a datum, in which case
+// there's no shift to apply between them.
+func compareDatums(src, dst *pj) bool {
+	if src.datumType != dst.datumType {
+		return false
+	} else if src.aOrig != dst.aOrig || math.Abs(src.esOrig-dst.esOrig) > 0.000000000050 {
+		return false
+	}
+	switch src.datumType {
+	case PJD_3PARAM, PJD_7PARAM:
+		for i := range src.datumParams {
+			if src.datumParams[i] != dst.datumParams[i] {
+				return false
+			}
+		}
+	case PJD_GRIDSHIFT:
+		return src.nadgrids == dst.nadgrids
+	}
+	return true
+}
+
+func datumTransform(src, dst *pj, x, y, z []float64) error {
+	if src.datumType == PJD_UNKNOWN || dst.datumType == PJD_UNKNOWN {
+		return nil
+	}
+	if compareDatums(src, dst) {
+		return nil
+	}
+	srcA, srcEs := src.aOrig, src.esOrig
+	dstA, dstEs := dst.aOrig, dst.esOrig
+
+	// grid shifts take geodetic coordinates to WGS84 (or something close
+	// enough to it, like NAD83)
+	var dstGrids []*grid
+	if src.datumType == PJD_GRIDSHIFT {
+		grids, err := src.grids()
+		if err != nil {
+			return err
+		}
+		if err := src.applyGridshift(grids, false, x, y); err != nil {
+			return err
+		}
+		srcA, srcEs = wgs84A, wgs84Es
+	}
+	if dst.datumType == PJD_GRIDSHIFT {
+		var err error
+		if dstGrids, err = dst.grids(); err != nil {
+			return err
+		}
+		dstA, dstEs = wgs84A, wgs84Es
+	}
+
+	// we only need to go through geocentric coordinates if the ellipsoid or
+	// the datum actually changes
+	if srcEs != dstEs || srcA != dstA || src.isParamDatum() || dst.isParamDatum() {
+		if err := geocentricShift(src, dst, srcA, srcEs, dstA, dstEs, x, y, z); err != nil {
+			return err
+		}
+	}
+
+	if dst.datumType == PJD_GRIDSHIFT {
+		return dst.applyGridshift(dstGrids, true, x, y)
+	}
+	return nil
+}
+
+// geocentricShift moves the points from the src ellipsoid to the dst one,
+// applying any Helmert shifts on the way through WGS84.
+func geocentricShift(src, dst *pj, srcA, srcEs, dstA, dstEs float64, x, y, z []float64) error {
+	for i := range x {
+		if x[i] == hugeVal {
+			continue
+		}
+		gx, gy, gz, err := geodeticToGeocentric(srcA, srcEs, x[i], y[i], z[i])
+		if err != nil {
+			return src.coordError(err, x[i], y[i])
+		}
+		if src.isParamDatum() {
+			gx, gy, gz = src.geocentricToWGS84(gx, gy, gz)
+		}
+		if dst.isParamDatum() {
+			gx, gy, gz = dst.geocentricFromWGS84(gx, gy, gz)
+		}
+		x[i], y[i], z[i] = geocentricToGeodetic(dstA, dstEs, gx, gy, gz)
+	}
+	return nil
+}
+
+// grids loads the +nadgrids list.
+func (p *pj) grids() ([]*grid, error) {
+	if p.nadgrids == "" {
+		// only +catalog, which we can't use
+		return nil, p.paramError("nadgrids", ErrUnknownDatum)
+	}
+	grids, err := p.gridSource.loadList(p.nadgrids, false)
+	if err != nil {
+		return nil, p.paramError("nadgrids", err)
+	}
+	return grids, nil
+}
+
+// applyGeoid converts z between orthometric heights in the vertical units
+// and ellipsoidal heights in metres, if there are +geoidgrids.
+func (p *pj) applyGeoid(inverse bool, x, y, z []float64) error {
+	if !inverse {
+		scaleHeights(p.vto_meter, x, z)
+	}
+	if p.geoidgrids != "" {
+		grids, err := p.gridSource.loadList(p.geoidgrids, true)
+		if err != nil {
+			return p.paramError("geoidgrids", err)
+		}
+		if err := p.applyVGridshift(grids, inverse, x, y, z); err != nil {
+			return err
+		}
+	}
+	if inverse {
+		scaleHeights(p.vfr_meter, x, z)
+	}
+	return nil
+}
+
+func scaleHeights(f float64, x, z []float64) {
+	if f == 1 {
+		return
+	}
+	for i := range z {
+		if x[i] != hugeVal {
+			z[i] *= f
+		}
+	}
+}
+
+func (p *pj) isParamDatum() bool {
+	return p.datumType == PJD_3PARAM || p.datumType == PJD_7PARAM
+}
+
+// geocentricToWGS84 applies the towgs84 Helmert shift.
+func (p *pj) geocentricToWGS84(x, y, z float64) (float64, float64, float64) {
+	dp := p.datumParams
+	if p.datumType == PJD_3PARAM {
+		return x + dp[0], y + dp[1], z + dp[2]
+	}
+	dx, dy, dz := dp[0], dp[1], dp[2]
+	rx, ry, rz, m := dp[3], dp[4], dp[5], dp[6]
+	return m*(x-rz*y+ry*z) + dx,
+		m*(rz*x+y-rx*z) + dy,
+		m*(-ry*x+rx*y+z) + dz
+}
+
+// geocentricFromWGS84 reverses the towgs84 Helmert shift.
+func (p *pj) geocentricFromWGS84(x, y, z float64) (float64, float64, float64) {
+	dp := p.datumParams
+	if p.datumType == PJD_3PARAM {
+		return x - dp[0], y - dp[1], z - dp[2]
+	}
+	dx, dy, dz := dp[0], dp[1], dp[2]
+	rx, ry, rz, m := dp[3], dp[4], dp[5], dp[6]
+	x = (x - dx) / m
+	y = (y - dy) / m
+	z = (z - dz) / m
+	return x + rz*y - ry*z,
+		-rz*x + y + rx*z,
+		ry*x - rx*y + z
+}
+
+// geodeticToGeocentric converts lng/lat (radians) and ellipsoidal height
+// to Earth-centred XYZ on the ellipsoid a/es.
+func geodeticToGeocentric(a, es, lng, lat, h float64) (x, y, z float64, err error) {
+	if lat < -half_pi && lat > -1.001*half_pi {
+		lat = -half_pi
+	} else if lat > half_pi && lat < 1.001*half_pi {
+		lat = half_pi
+	} else if lat < -half_pi || lat > half_pi {
+		return hugeVal, hugeVal, hugeVal, ErrToleranceCondition
+	}
+	if lng > math.Pi {
+		lng -= two_pi
+	}
+	sinlat, coslat := math.Sin(lat), math.Cos(lat)
+	rn := a / math.Sqrt(1-es*sinlat*sinlat)
+	x = (rn + h) * coslat * math.Cos(lng)
+	y = (rn + h) * coslat * math.Sin(lng)
+	z = (rn*(1-es) + h) * sinlat
+	return x, y, z, nil
+}
+
+// geocentricToGeodetic converts Earth-centred XYZ to lng/lat (radians) and
+// ellipsoidal height on the ellipsoid a/es, iterating to 1e-12 radians,
+// which is well under 0.1mm on the Earth.
+func geocentricToGeodetic(a, es, x, y, z float64) (lng, lat, h float64) {
+	const genau = 1.0e-12
+	b := a * math.Sqrt(1-es)
+	p := math.Hypot(x, y)
+	rr := math.Sqrt(x*x + y*y + z*z)
+	if p/a < genau {
+		// on the polar axis
+		if rr/a < genau {
+			return 0, half_pi, -b
+		}
+	} else {
+		lng = math.Atan2(y, x)
+	}
+	ct := z / rr
+	st := p / rr
+	rx := 1 / math.Sqrt(1-es*(2-es)*st*st)
+	cphi0 := st * (1 - es) * rx
+	sphi0 := ct * rx
+	var cphi, sphi float64
+	for i := 0; i < 30; i++ {
+		rn := a / math.Sqrt(1-es*sphi0*sphi0)
+		h = p*cphi0 + z*sphi0 - rn*(1-es*sphi0*sphi0)
+		rk := es * rn / (rn + h)
+		rx = 1 / math.Sqrt(1-rk*(2-rk)*st*st)
+		cphi = st * (1 - rk) * rx
+		sphi = ct * rx
+		sdphi := sphi*cphi0 - cphi*sphi0
+		cphi0 = cphi
+		sphi0 = sphi
+		if sdphi*sdphi <= genau*genau {
+			break
+		}
+	}
+	lat = math.Atan(sphi / math.Abs(cphi))
+	return lng, lat, h
+}
@@ -0,0 +1,220 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestGeocentricRoundTrip(t *testing.T) {
+	a, es := 6378137.0, 0.00669437999014
+	for _, ll := range [][3]float64{{0, 0, 0}, {-77, 38.9, 100}, {135, -89.9, -20}, {10, 90, 5000}} {
+		lng0, lat0, h0 := ll[0]*d2r, ll[1]*d2r, ll[2]
+		x, y, z, err := geodeticToGeocentric(a, es, lng0, lat0, h0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lng1, lat1, h1 := geocentricToGeodetic(a, es, x, y, z)
+		if math.Abs(lat0-half_pi) > epsln && !within(lng0, lng1, 1.0e-12) {
+			t.Errorf("lng off: %f - %f", lng0, lng1)
+		}
+		if !within(lat0, lat1, 1.0e-12) || !within(h0, h1, 1.0e-6) {
+			t.Errorf("lat/h off: (%f, %f) - (%f, %f)", lat0, h0, lat1, h1)
+		}
+	}
+}
+
+func TestTransformOSGB36(t *testing.T) {
+	wgs84, err := NewProjection("+proj=longlat +datum=WGS84")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bng, err := NewProjection("+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +datum=OSGB36 +units=m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Caister water tower, from the Ordnance Survey's guide to coordinate
+	// systems, in OSGB36
+	e0, n0 := 651409.903, 313177.270
+	lng0 := (1 + 43.0/60 + 4.5177/3600) * d2r
+	lat0 := (52 + 39.0/60 + 27.2531/3600) * d2r
+
+	// shift it to WGS84 by hand with the 7 parameters from datums_list
+	gx, gy, gz, _ := geodeticToGeocentric(6377563.396, 1-(6356256.910*6356256.910)/(6377563.396*6377563.396), lng0, lat0, 0)
+	rx, ry, rz, m := 0.1502*sec2rad, 0.2470*sec2rad, 0.8421*sec2rad, 1-20.4894e-6
+	expLng, expLat, _ := geocentricToGeodetic(6378137.0, 0.0066943799901413165,
+		m*(gx-rz*gy+ry*gz)+446.448,
+		m*(rz*gx+gy-rx*gz)-125.157,
+		m*(-ry*gx+rx*gy+gz)+542.060)
+
+	x, y := []float64{e0}, []float64{n0}
+	if err := Transform(bng, wgs84, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !within(expLng, x[0], 1.0e-9) || !within(expLat, y[0], 1.0e-9) {
+		t.Errorf("inv transform off: (%.10f, %.10f) - (%.10f, %.10f)", expLng, expLat, x[0], y[0])
+	}
+
+	// and back again
+	if err := Transform(wgs84, bng, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !within(e0, x[0], 1.0e-3) || !within(n0, y[0], 1.0e-3) {
+		t.Errorf("fwd transform off: (%f, %f) - (%f, %f)", e0, n0, x[0], y[0])
+	}
+
+	// without the datum shift the same coordinates land over 100m away
+	x, y = []float64{lng0}, []float64{lat0}
+	Transform(wgs84, bng, x, y, nil)
+	if d := math.Hypot(x[0]-e0, y[0]-n0); d < 100 || d > 150 {
+		t.Errorf("expected a shift of 100-150m, got %f", d)
+	}
+}
+
+func TestTransformHeights(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	ggrs87, _ := NewProjection("+proj=longlat +datum=GGRS87")
+
+	x, y, z := []float64{23.7 * d2r}, []float64{37.9 * d2r}, []float64{100}
+	if err := Transform(ggrs87, wgs84, x, y, z); err != nil {
+		t.Fatal(err)
+	}
+	// a 3 parameter shift moves the point by the length of the shift
+	x0, y0, z0, _ := geodeticToGeocentric(6378137.0, 0.00669438002290, 23.7*d2r, 37.9*d2r, 100)
+	x1, y1, z1, _ := geodeticToGeocentric(6378137.0, 0.00669437999014, x[0], y[0], z[0])
+	if !within(x1-x0, -199.87, 1.0e-3) || !within(y1-y0, 74.79, 1.0e-3) || !within(z1-z0, 246.62, 1.0e-3) {
+		t.Errorf("shift off: (%f, %f, %f)", x1-x0, y1-y0, z1-z0)
+	}
+}
+
+func TestTransformSameDatum(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	merc, _ := NewProjection("+proj=merc +datum=WGS84")
+
+	lng0, lat0 := 18.5*d2r, 54.2*d2r
+	x, y := []float64{lng0}, []float64{lat0}
+	if err := Transform(wgs84, merc, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	expx, expy, _ := merc.Forward(lng0, lat0)
+	if x[0] != expx || y[0] != expy {
+		t.Errorf("expected a plain forward projection: (%f, %f) - (%f, %f)", expx, expy, x[0], y[0])
+	}
+
+	if err := Transform(wgs84, merc, []float64{0, 1}, []float64{0}, nil); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam for mismatched slices, got %v", err)
+	}
+}
+
+func TestGeocentric(t *testing.T) {
+	proj, _ := NewProjection("+proj=longlat +datum=WGS84")
+	wgs84 := proj.(GeocentricConverter)
+	tests := []struct {
+		lng, lat, h float64
+		x, y, z     float64
+	}{
+		{0, 0, 0, 6378137, 0, 0},
+		{0, 90, 0, 0, 0, 6356752.314245},
+		{-90, -90, 10, 0, 0, -6356762.314245},
+		// EPSG guidance note 7-2, on WGS 84 rather than ETRS89/GRS80
+		{2 + 7.0/60 + 46.38/3600, 53 + 48.0/60 + 33.82/3600, 73, 3771793.968, 140253.342, 5124304.349},
+	}
+	for _, tt := range tests {
+		x, y, z, err := wgs84.ToGeocentric(tt.lng*d2r, tt.lat*d2r, tt.h)
+		if err != nil {
+			t.Error(err)
+		}
+		if !within(tt.x, x, 1.0e-3) || !within(tt.y, y, 1.0e-3) || !within(tt.z, z, 1.0e-3) {
+			t.Errorf("geocentric off: (%f, %f, %f) - (%f, %f, %f)", tt.x, tt.y, tt.z, x, y, z)
+		}
+		_, lat, h := wgs84.FromGeocentric(x, y, z)
+		if !within(tt.lat*d2r, lat, 1.0e-11) || !within(tt.h, h, 1.0e-4) {
+			t.Errorf("geodetic off: (%f, %f) - (%f, %f)", tt.lat*d2r, tt.h, lat, h)
+		}
+	}
+
+	if _, _, _, err := wgs84.ToGeocentric(0, 2, 0); !errors.Is(err, ErrToleranceCondition) {
+		t.Errorf("expected a tolerance condition for lat > 90, got %v", err)
+	}
+}
+
+func TestTransformGeocent(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	geocent, err := NewProjection("+proj=geocent +datum=WGS84")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := geocent.Forward(0, 0); !errors.Is(err, ErrGeocentric) {
+		t.Errorf("expected ErrGeocentric, got %v", err)
+	}
+
+	lng0, lat0 := (2+7.0/60+46.38/3600)*d2r, (53+48.0/60+33.82/3600)*d2r
+	x, y, z := []float64{lng0}, []float64{lat0}, []float64{73}
+	if err := Transform(wgs84, geocent, x, y, z); err != nil {
+		t.Fatal(err)
+	}
+	if !within(3771793.968, x[0], 1.0e-3) || !within(140253.342, y[0], 1.0e-3) || !within(5124304.349, z[0], 1.0e-3) {
+		t.Errorf("geocentric off: (%f, %f, %f)", x[0], y[0], z[0])
+	}
+	if err := Transform(geocent, wgs84, x, y, z); err != nil {
+		t.Fatal(err)
+	}
+	if !within(lng0, x[0], 1.0e-11) || !within(lat0, y[0], 1.0e-11) || !within(73, z[0], 1.0e-4) {
+		t.Errorf("geodetic off: (%f, %f, %f)", x[0], y[0], z[0])
+	}
+
+	if err := Transform(geocent, wgs84, x, y, nil); !errors.Is(err, ErrGeocentric) {
+		t.Errorf("expected ErrGeocentric without heights, got %v", err)
+	}
+	// there'd be nowhere to put Z
+	x, y = []float64{lng0}, []float64{lat0}
+	if err := Transform(wgs84, geocent, x, y, nil); !errors.Is(err, ErrGeocentric) {
+		t.Errorf("expected ErrGeocentric without heights, got %v", err)
+	}
+	if x[0] != lng0 || y[0] != lat0 {
+		t.Errorf("expected the points to be left alone, got (%f, %f)", x[0], y[0])
+	}
+}
+
+func TestTransformAxis(t *testing.T) {
+	latlng, err := NewProjectionFromWKT(wgs84GEOGCRS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lnglat, _ := NewProjection("+proj=longlat +datum=WGS84")
+	utm, _ := NewProjection("+proj=utm +zone=33 +datum=WGS84")
+	lng, lat := 16.75*d2r, 52.5*d2r
+
+	e, n := []float64{lng}, []float64{lat}
+	if err := Transform(lnglat, utm, e, n, nil); err != nil {
+		t.Fatal(err)
+	}
+	x, y := []float64{lat}, []float64{lng}
+	if err := Transform(latlng, utm, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if x[0] != e[0] || y[0] != n[0] {
+		t.Errorf("expected (%f, %f), got (%f, %f)", e[0], n[0], x[0], y[0])
+	}
+	if err := Transform(utm, latlng, x, y, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !within(lat, x[0], 1e-11) || !within(lng, y[0], 1e-11) {
+		t.Errorf("expected (%f, %f), got (%f, %f)", lat, lng, x[0], y[0])
+	}
+
+	// heights go down
+	ned, _ := NewProjection("+proj=longlat +datum=WGS84 +axis=ned")
+	x, y, z := []float64{lng}, []float64{lat}, []float64{100}
+	if err := Transform(lnglat, ned, x, y, z); err != nil {
+		t.Fatal(err)
+	}
+	if x[0] != lat || y[0] != lng || z[0] != -100 {
+		t.Errorf("expected (%f, %f, -100), got (%f, %f, %f)", lat, lng, x[0], y[0], z[0])
+	}
+}
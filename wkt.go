@@ -0,0 +1,417 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"strconv"
+	"strings"
+)
+
+// wktNode is a keyword and its bracketed contents.  Quoted strings, numbers
+// and bare enumerations like NORTH are kept in order in values, nested
+// keywords in children.
+type wktNode struct {
+	keyword  string
+	values   []string
+	children []*wktNode
+}
+
+// child returns the first child with one of the given keywords, ignoring
+// case, or nil.  It's safe to call on a nil node.
+func (n *wktNode) child(keywords ...string) *wktNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		for _, k := range keywords {
+			if strings.EqualFold(c.keyword, k) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// value returns the i'th value, or "" if there isn't one.
+func (n *wktNode) value(i int) string {
+	if n == nil || i >= len(n.values) {
+		return ""
+	}
+	return n.values[i]
+}
+
+// float returns the i'th value as a number.
+func (n *wktNode) float(i int) (float64, bool) {
+	f, err := strconv.ParseFloat(n.value(i), 64)
+	return f, err == nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+// parseWKT parses any WKT string into a tree of nodes.
+func parseWKT(s string) (*wktNode, error) {
+	p := &wktParser{s: s}
+	n, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos != len(p.s) {
+		return nil, ErrInvalidWKT
+	}
+	return n, nil
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// word reads a keyword, number or enumeration.
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n,[]()\"", p.s[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// quoted reads a quoted string, in which "" stands for a quote.
+func (p *wktParser) quoted() (string, error) {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		if p.s[p.pos] == '"' {
+			if p.pos+1 < len(p.s) && p.s[p.pos+1] == '"' {
+				p.pos++
+			} else {
+				p.pos++
+				return b.String(), nil
+			}
+		}
+		b.WriteByte(p.s[p.pos])
+	}
+	return "", ErrInvalidWKT
+}
+
+func (p *wktParser) node() (*wktNode, error) {
+	n := &wktNode{keyword: p.word()}
+	if n.keyword == "" || p.pos >= len(p.s) || (p.s[p.pos] != '[' && p.s[p.pos] != '(') {
+		return nil, ErrInvalidWKT
+	}
+	close := byte(']')
+	if p.s[p.pos] == '(' {
+		close = ')'
+	}
+	p.pos++
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, ErrInvalidWKT
+		}
+		if p.s[p.pos] == '"' {
+			v, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+		} else {
+			start := p.pos
+			w := p.word()
+			if w == "" {
+				return nil, ErrInvalidWKT
+			}
+			if p.pos < len(p.s) && (p.s[p.pos] == '[' || p.s[p.pos] == '(') {
+				p.pos = start
+				c, err := p.node()
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, c)
+			} else {
+				n.values = append(n.values, w)
+			}
+		}
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, ErrInvalidWKT
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case close:
+			p.pos++
+			return n, nil
+		default:
+			return nil, ErrInvalidWKT
+		}
+	}
+}
+
+// wktName normalises the names used by OGC and ESRI so they can be looked up.
+func wktName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// wktProjections maps OGC and ESRI projection names onto our projections.
+var wktProjections = map[string]string{
+	"transverse_mercator":          "tmerc",
+	"gauss_kruger":                 "tmerc",
+	"mercator":                     "merc",
+	"mercator_1sp":                 "merc",
+	"mercator_2sp":                 "merc",
+	"mercator_auxiliary_sphere":    "merc",
+	"lambert_conformal_conic":      "lcc",
+	"lambert_conformal_conic_1sp":  "lcc",
+	"lambert_conformal_conic_2sp":  "lcc",
+	"polar_stereographic":          "stere",
+	"stereographic_north_pole":     "stere",
+	"stereographic_south_pole":     "stere",
+	"stereographic":                "stere",
+	"oblique_stereographic":        "sterea",
+	"double_stereographic":         "sterea",
+	"swiss_oblique_cylindrical":    "somerc",
+	"albers_conic_equal_area":      "aea",
+	"albers":                       "aea",
+	"lambert_azimuthal_equal_area": "laea",
+	"azimuthal_equidistant":        "aeqd",
+	"orthographic":                 "ortho",
+	"gnomonic":                     "gnom",
+	"equirectangular":              "eqc",
+	"equidistant_cylindrical":      "eqc",
+	"plate_carree":                 "eqc",
+}
+
+// wktParameters maps OGC and ESRI parameter names onto proj4 keys.
+// Anything else is ignored.
+var wktParameters = map[string]string{
+	"latitude_of_origin":  "lat_0",
+	"latitude_of_center":  "lat_0",
+	"central_meridian":    "lon_0",
+	"longitude_of_center": "lon_0",
+	"longitude_of_origin": "lon_0",
+	"standard_parallel_1": "lat_1",
+	"standard_parallel_2": "lat_2",
+	"scale_factor":        "k_0",
+	"false_easting":       "x_0",
+	"false_northing":      "y_0",
+}
+
+// wktDatums maps datum names that don't match a datums_list comment onto
+// its entries.
+var wktDatums = map[string]string{
+	"wgs_1984":                              "WGS84",
+	"world_geodetic_system_1984":            "WGS84",
+	"north_american_1983":                   "NAD83",
+	"north_american_1927":                   "NAD27",
+	"ggrs_1987":                             "GGRS87",
+	"osgb_1936":                             "OSGB36",
+	"ordnance_survey_of_great_britain_1936": "OSGB36",
+	"deutsches_hauptdreiecksnetz":           "potsdam",
+	"ireland_1965":                          "ire65",
+	"tm65":                                  "ire65",
+	"new_zealand_1949":                      "nzgd49",
+	"world_geodetic_system_1984_ensemble":   "WGS84",
+	"carthage":                              "carthage",
+}
+
+// NewProjectionFromWKT creates a Projection from an OGC WKT1 description,
+// like those in .prj files and GeoTIFFs, including ESRI's flavour of it, or
+// from an ISO 19162 WKT2 one.
+func NewProjectionFromWKT(wkt string) (Projection, error) {
+	root, err := parseWKT(wkt)
+	if err != nil {
+		return nil, err
+	}
+	var parms paramset
+	switch strings.ToUpper(root.keyword) {
+	case "PROJCS", "GEOGCS", "GEOCCS":
+		parms, err = wkt1Params(root)
+	default:
+		parms, err = wkt2Params(root)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newProjection(parms)
+}
+
+// wkt1Params maps a PROJCS, GEOGCS or GEOCCS node onto the parameters a
+// proj4 string would have.
+func wkt1Params(root *wktNode) (paramset, error) {
+	parms := make(paramset)
+	if ext := root.child("EXTENSION"); ext != nil && strings.EqualFold(ext.value(0), "PROJ4") {
+		return parseParams(ext.value(1)), nil
+	}
+
+	var geogcs *wktNode
+	switch strings.ToUpper(root.keyword) {
+	case "PROJCS":
+		if geogcs = root.child("GEOGCS"); geogcs == nil {
+			return nil, ErrInvalidWKT
+		}
+	case "GEOGCS":
+		geogcs = root
+		parms["proj"] = "longlat"
+	case "GEOCCS":
+		geogcs = root
+		parms["proj"] = "geocent"
+	default:
+		return nil, ErrUnsupportedProj
+	}
+
+	// angles are in the GEOGCS's unit, lengths in the PROJCS's or GEOCCS's
+	angular, linear := d2r, 1.0
+	if f, ok := geogcs.child("UNIT").float(1); ok && f > 0 && parms["proj"] != "geocent" {
+		angular = f
+	}
+	if f, ok := root.child("UNIT").float(1); ok && f > 0 && parms["proj"] != "longlat" {
+		linear = f
+	}
+	// the conversion factors are often rounded
+	angular = snapDegree(angular)
+	degrees := func(v float64) string { return formatFloat(v * angular / d2r) }
+
+	if linear != 1 {
+		parms["to_meter"] = formatFloat(linear)
+	}
+
+	if err := wkt1Datum(geogcs, parms); err != nil {
+		return nil, err
+	}
+	if pm := geogcs.child("PRIMEM"); pm != nil {
+		if lng, ok := pm.float(1); ok && lng != 0 {
+			parms["pm"] = degrees(lng)
+		}
+	}
+
+	if !strings.EqualFold(root.keyword, "PROJCS") {
+		return parms, nil
+	}
+	method := wktName(root.child("PROJECTION").value(0))
+	proj, ok := wktProjections[method]
+	if !ok {
+		return nil, ErrUnsupportedProj
+	}
+	parms["proj"] = proj
+	for _, c := range root.children {
+		if !strings.EqualFold(c.keyword, "PARAMETER") {
+			continue
+		}
+		key, ok := wktParameters[wktName(c.value(0))]
+		v, isNum := c.float(1)
+		if !ok || !isNum {
+			continue
+		}
+		switch key {
+		case "x_0", "y_0":
+			parms[key] = formatFloat(v * linear)
+		case "k_0":
+			parms[key] = formatFloat(v)
+		default:
+			parms[key] = degrees(v)
+		}
+	}
+
+	// the same parameters mean different things to different methods
+	switch method {
+	case "mercator_2sp", "mercator", "equirectangular", "equidistant_cylindrical":
+		if lat1, ok := parms["lat_1"]; ok {
+			parms["lat_ts"] = lat1
+			delete(parms, "lat_1")
+		}
+	case "mercator_auxiliary_sphere":
+		// web mercator, which is spherical on the WGS84 datum
+		for _, key := range []string{"a", "rf", "datum", "towgs84", "nadgrids"} {
+			delete(parms, key)
+		}
+		parms["R"] = "6378137"
+		parms["nadgrids"] = "@null"
+	case "lambert_conformal_conic_1sp":
+		parms["lat_1"] = parms["lat_0"]
+	case "polar_stereographic", "stereographic_north_pole", "stereographic_south_pole":
+		lat, ok := parms.degree("lat_1")
+		if !ok {
+			lat, _ = parms.degree("lat_0")
+		}
+		delete(parms, "lat_1")
+		parms["lat_ts"] = formatFloat(lat / d2r)
+		if lat < 0 || method == "stereographic_south_pole" {
+			parms["lat_0"] = "-90"
+		} else {
+			parms["lat_0"] = "90"
+		}
+	}
+	return parms, nil
+}
+
+// wkt1Datum fills in the ellipsoid and datum shift from a GEOGCS or GEOCCS.
+func wkt1Datum(geogcs *wktNode, parms paramset) error {
+	datum := geogcs.child("DATUM")
+	if err := wktEllipsoid(datum.child("SPHEROID", "ELLIPSOID"), parms); err != nil {
+		return err
+	}
+
+	if towgs84 := datum.child("TOWGS84"); towgs84 != nil {
+		params := towgs84.values
+		if len(params) == 7 && params[3] == "0" && params[4] == "0" && params[5] == "0" && params[6] == "0" {
+			params = params[:3]
+		}
+		if len(params) != 3 && len(params) != 7 {
+			return ErrInvalidWKT
+		}
+		parms["towgs84"] = strings.Join(params, ",")
+	} else if id, ok := wktDatum(datum.value(0)); ok {
+		// otherwise use what we know about the datum
+		parms["datum"] = id
+	}
+	return nil
+}
+
+// wktEllipsoid fills in the size and shape of a SPHEROID or ELLIPSOID.
+func wktEllipsoid(ellipsoid *wktNode, parms paramset) error {
+	a, ok := ellipsoid.float(1)
+	rf, ok2 := ellipsoid.float(2)
+	if !ok || !ok2 || a <= 0 {
+		return ErrInvalidWKT
+	}
+	if f, ok := ellipsoid.child("LENGTHUNIT", "UNIT").float(1); ok && f > 0 {
+		a *= f
+	}
+	if rf == 0 {
+		parms["R"] = formatFloat(a)
+	} else {
+		parms["a"] = formatFloat(a)
+		parms["rf"] = formatFloat(rf)
+	}
+	return nil
+}
+
+// wktDatum finds the datums_list entry for a datum's name.
+func wktDatum(name string) (string, bool) {
+	name = strings.TrimPrefix(wktName(name), "d_")
+	if id, ok := wktDatums[name]; ok {
+		return id, true
+	}
+	for id, d := range datums_list {
+		if d.comments != "" && wktName(d.comments) == name {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
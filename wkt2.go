@@ -0,0 +1,684 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type wkt2Kind int
+
+const (
+	wkt2Angle wkt2Kind = iota
+	wkt2Length
+	wkt2Scale
+)
+
+// wkt2Param is an EPSG parameter and the proj4 key it maps onto.
+type wkt2Param struct {
+	code, name, key string
+	kind            wkt2Kind
+}
+
+var wkt2Parameters = []wkt2Param{
+	{"8801", "Latitude of natural origin", "lat_0", wkt2Angle},
+	{"8802", "Longitude of natural origin", "lon_0", wkt2Angle},
+	{"8805", "Scale factor at natural origin", "k_0", wkt2Scale},
+	{"8806", "False easting", "x_0", wkt2Length},
+	{"8807", "False northing", "y_0", wkt2Length},
+	{"8811", "Latitude of projection centre", "lat_0", wkt2Angle},
+	{"8812", "Longitude of projection centre", "lon_0", wkt2Angle},
+	{"8821", "Latitude of false origin", "lat_0", wkt2Angle},
+	{"8822", "Longitude of false origin", "lon_0", wkt2Angle},
+	{"8823", "Latitude of 1st standard parallel", "lat_1", wkt2Angle},
+	{"8824", "Latitude of 2nd standard parallel", "lat_2", wkt2Angle},
+	{"8826", "Easting at false origin", "x_0", wkt2Length},
+	{"8827", "Northing at false origin", "y_0", wkt2Length},
+	{"8832", "Latitude of standard parallel", "lat_ts", wkt2Angle},
+	{"8833", "Longitude of origin", "lon_0", wkt2Angle},
+}
+
+// wkt2Method is an EPSG (or PROJ, where there's no code) method, the
+// projection it maps onto and the parameters it takes.
+type wkt2Method struct {
+	code, name, proj string
+	params           []string
+}
+
+var (
+	naturalOrigin = []string{"8801", "8802", "8805", "8806", "8807"}
+	falseOrigin   = []string{"8821", "8822", "8823", "8824", "8826", "8827"}
+	azimuthal     = []string{"8801", "8802", "8806", "8807"}
+)
+
+var wkt2Methods = []wkt2Method{
+	{"9807", "Transverse Mercator", "tmerc", naturalOrigin},
+	{"9804", "Mercator (variant A)", "merc", naturalOrigin},
+	{"9805", "Mercator (variant B)", "merc", []string{"8823", "8802", "8806", "8807"}},
+	{"1024", "Popular Visualisation Pseudo Mercator", "merc", azimuthal},
+	{"9801", "Lambert Conic Conformal (1SP)", "lcc", naturalOrigin},
+	{"9802", "Lambert Conic Conformal (2SP)", "lcc", falseOrigin},
+	{"9810", "Polar Stereographic (variant A)", "stere", naturalOrigin},
+	{"9829", "Polar Stereographic (variant B)", "stere", []string{"8832", "8833", "8806", "8807"}},
+	{"", "Stereographic", "stere", naturalOrigin},
+	{"9809", "Oblique Stereographic", "sterea", naturalOrigin},
+	{"9822", "Albers Equal Area", "aea", falseOrigin},
+	{"9820", "Lambert Azimuthal Equal Area", "laea", azimuthal},
+	{"1125", "Azimuthal Equidistant", "aeqd", azimuthal},
+	{"9840", "Orthographic", "ortho", azimuthal},
+	{"", "Gnomonic", "gnom", azimuthal},
+	{"1028", "Equidistant Cylindrical", "eqc", []string{"8823", "8802", "8806", "8807"}},
+}
+
+// wkt2Shifts are the parameters of the Helmert transformations in a
+// BOUNDCRS, in towgs84 order.
+var wkt2Shifts = []wkt2Param{
+	{"8605", "X-axis translation", "", wkt2Length},
+	{"8606", "Y-axis translation", "", wkt2Length},
+	{"8607", "Z-axis translation", "", wkt2Length},
+	{"8608", "X-axis rotation", "", wkt2Angle},
+	{"8609", "Y-axis rotation", "", wkt2Angle},
+	{"8610", "Z-axis rotation", "", wkt2Angle},
+	{"8611", "Scale difference", "", wkt2Scale},
+}
+
+// wktID returns the code of a node's EPSG ID, if it has one.
+func wktID(n *wktNode) string {
+	if id := n.child("ID", "AUTHORITY"); id != nil && strings.EqualFold(id.value(0), "EPSG") {
+		return id.value(1)
+	}
+	return ""
+}
+
+// wktUnit returns the conversion factor of a node's unit, or def.
+func wktUnit(n *wktNode, def float64) float64 {
+	if f, ok := n.child("UNIT", "ANGLEUNIT", "LENGTHUNIT", "SCALEUNIT").float(1); ok && f > 0 {
+		return f
+	}
+	return def
+}
+
+// wkt2Params maps a PROJCRS, GEOGCRS or GEODCRS, or a BOUNDCRS around one,
+// onto the parameters a proj4 string would have.
+func wkt2Params(root *wktNode) (paramset, error) {
+	parms := make(paramset)
+	crs := root
+	if strings.EqualFold(root.keyword, "BOUNDCRS") {
+		if crs = root.child("SOURCECRS"); crs == nil || len(crs.children) == 0 {
+			return nil, ErrInvalidWKT
+		}
+		crs = crs.children[0]
+		if err := wkt2Shift(root.child("ABRIDGEDTRANSFORMATION"), parms); err != nil {
+			return nil, err
+		}
+	}
+
+	var base *wktNode
+	switch strings.ToUpper(crs.keyword) {
+	case "PROJCRS", "PROJECTEDCRS":
+		base = crs.child("BASEGEOGCRS", "BASEGEODCRS", "BASEGEOGRAPHICCRS", "BASEGEODETICCRS")
+		if base == nil {
+			return nil, ErrInvalidWKT
+		}
+	case "GEOGCRS", "GEOGRAPHICCRS", "GEODCRS", "GEODETICCRS":
+		base = crs
+		parms["proj"] = "longlat"
+		if strings.EqualFold(crs.child("CS").value(0), "Cartesian") {
+			parms["proj"] = "geocent"
+		}
+	default:
+		return nil, ErrUnsupportedProj
+	}
+	if err := wkt2Datum(base, parms); err != nil {
+		return nil, err
+	}
+
+	// axes, and their unit if they're lengths
+	var axes []*wktNode
+	for _, c := range crs.children {
+		if strings.EqualFold(c.keyword, "AXIS") {
+			axes = append(axes, c)
+		}
+	}
+	sort.SliceStable(axes, func(i, j int) bool {
+		oi, _ := axes[i].child("ORDER").float(0)
+		oj, _ := axes[j].child("ORDER").float(0)
+		return oi < oj
+	})
+	linear := 1.0
+	if parms["proj"] != "longlat" {
+		linear = wktUnit(crs, 1)
+		if len(axes) > 0 {
+			linear = wktUnit(axes[0], linear)
+		}
+	}
+	if linear != 1 {
+		parms["to_meter"] = formatFloat(linear)
+	}
+	if parms["proj"] != "geocent" && (len(axes) == 2 || len(axes) == 3) {
+		axis := ""
+		for _, a := range axes {
+			dir := strings.ToLower(a.value(1))
+			if dir == "" || !strings.Contains("ewnsud", dir[:1]) {
+				return nil, ErrInvalidWKT
+			}
+			axis += dir[:1]
+		}
+		if len(axis) == 2 {
+			axis += "u"
+		}
+		// polar projections give their axes along meridians, like
+		// north and north, which we take to be the usual ones
+		if axis != "enu" && validAxis(axis) {
+			parms["axis"] = axis
+		}
+	}
+
+	if parms["proj"] != "" {
+		return parms, nil
+	}
+	return parms, wkt2Conversion(crs.child("CONVERSION"), linear, parms)
+}
+
+// wkt2Datum fills in the ellipsoid, datum and prime meridian of a CRS.
+func wkt2Datum(crs *wktNode, parms paramset) error {
+	datum := crs.child("DATUM", "GEODETICDATUM", "TRF", "ENSEMBLE")
+	if err := wktEllipsoid(datum.child("ELLIPSOID", "SPHEROID"), parms); err != nil {
+		return err
+	}
+	if _, ok := parms["towgs84"]; !ok {
+		if _, ok := parms["nadgrids"]; !ok {
+			if id, ok := wktDatum(datum.value(0)); ok {
+				parms["datum"] = id
+			}
+		}
+	}
+	pm := crs.child("PRIMEM", "PRIMEMERIDIAN")
+	if lng, ok := pm.float(1); ok && lng != 0 {
+		parms["pm"] = formatFloat(lng * snapDegree(wktUnit(pm, wktUnit(crs, d2r))) / d2r)
+	}
+	return nil
+}
+
+// wkt2Conversion fills in the projection and its parameters.
+func wkt2Conversion(conversion *wktNode, linear float64, parms paramset) error {
+	method := conversion.child("METHOD")
+	if method == nil {
+		return ErrInvalidWKT
+	}
+	code, name := wktID(method), wktName(method.value(0))
+	var m *wkt2Method
+	for i := range wkt2Methods {
+		if (code != "" && code == wkt2Methods[i].code) || name == wktName(wkt2Methods[i].name) {
+			m = &wkt2Methods[i]
+			break
+		}
+	}
+	if m == nil {
+		return ErrUnsupportedProj
+	}
+	parms["proj"] = m.proj
+
+	for _, c := range conversion.children {
+		if !strings.EqualFold(c.keyword, "PARAMETER") {
+			continue
+		}
+		p := findWKT2Param(wkt2Parameters, c)
+		v, ok := c.float(1)
+		if p == nil || !ok {
+			continue
+		}
+		switch p.kind {
+		case wkt2Angle:
+			parms[p.key] = formatFloat(v * snapDegree(wktUnit(c, d2r)) / d2r)
+		case wkt2Length:
+			parms[p.key] = formatFloat(v * wktUnit(c, linear))
+		case wkt2Scale:
+			parms[p.key] = formatFloat(v * wktUnit(c, 1))
+		}
+	}
+
+	// the same parameters mean different things to different methods
+	switch m.code {
+	case "9805", "1028":
+		if lat1, ok := parms["lat_1"]; ok {
+			parms["lat_ts"] = lat1
+			delete(parms, "lat_1")
+		}
+	case "1024":
+		// spherical on the WGS84 datum
+		parms["R"] = parms["a"]
+		for _, key := range []string{"a", "rf", "datum", "towgs84"} {
+			delete(parms, key)
+		}
+		parms["nadgrids"] = "@null"
+	case "9801":
+		parms["lat_1"] = parms["lat_0"]
+	case "9829":
+		if lat, _ := parms.degree("lat_ts"); lat < 0 {
+			parms["lat_0"] = "-90"
+		} else {
+			parms["lat_0"] = "90"
+		}
+	}
+	return nil
+}
+
+// wkt2Shift fills in the towgs84 or nadgrids of a BOUNDCRS.
+func wkt2Shift(tr *wktNode, parms paramset) error {
+	if tr == nil {
+		return ErrInvalidWKT
+	}
+	if file := tr.child("PARAMETERFILE"); file != nil {
+		parms["nadgrids"] = file.value(1)
+		return nil
+	}
+	method := tr.child("METHOD")
+	name := wktName(method.value(0))
+	frame := strings.Contains(name, "coordinate_frame")
+	switch wktID(method) {
+	case "9607", "1032", "1038":
+		frame = true
+	}
+
+	values := make([]float64, 7)
+	for _, c := range tr.children {
+		if !strings.EqualFold(c.keyword, "PARAMETER") {
+			continue
+		}
+		p := findWKT2Param(wkt2Shifts, c)
+		v, ok := c.float(1)
+		if p == nil || !ok {
+			continue
+		}
+		i := 0
+		for wkt2Shifts[i].code != p.code {
+			i++
+		}
+		switch p.kind {
+		case wkt2Length:
+			values[i] = v * wktUnit(c, 1)
+		case wkt2Angle:
+			values[i] = v * wktUnit(c, sec2rad) / sec2rad
+			if frame {
+				values[i] = -values[i]
+			}
+		case wkt2Scale:
+			values[i] = v * wktUnit(c, 1e-6) / 1e-6
+		}
+	}
+	if values[3] == 0 && values[4] == 0 && values[5] == 0 && values[6] == 0 {
+		values = values[:3]
+	}
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = formatFloat(v)
+	}
+	parms["towgs84"] = strings.Join(strs, ",")
+	return nil
+}
+
+// findWKT2Param matches a PARAMETER node by its ID or name.
+func findWKT2Param(params []wkt2Param, n *wktNode) *wkt2Param {
+	code, name := wktID(n), wktName(n.value(0))
+	for i := range params {
+		if (code != "" && code == params[i].code) || name == wktName(params[i].name) {
+			return &params[i]
+		}
+	}
+	return nil
+}
+
+func wkt2ParamByCode(code string) *wkt2Param {
+	for i := range wkt2Parameters {
+		if wkt2Parameters[i].code == code {
+			return &wkt2Parameters[i]
+		}
+	}
+	return nil
+}
+
+// snapDegree undoes the rounding of degrees' conversion factor.
+func snapDegree(f float64) float64 {
+	if math.Abs(f/d2r-1) < 1e-10 {
+		return d2r
+	}
+	return f
+}
+
+// FormatWKT2 describes a Projection made by NewProjection in ISO 19162:2019
+// WKT2.  Datum shifts are written as a BOUNDCRS to WGS84; vertical grids
+// are left out.
+func FormatWKT2(proj Projection) (string, error) {
+	imp, ok := proj.(impl)
+	if !ok {
+		return "", ErrUnsupportedProj
+	}
+	p := imp.base()
+	degree := wkt("ANGLEUNIT", wktQuote("degree"), "0.0174532925199433")
+	length := wkt("LENGTHUNIT", wktQuote(unitName(p.to_meter)), wktNumber(p.to_meter))
+
+	datum := p.wkt2Datum()
+	primem := wkt("PRIMEM", wktQuote(p.pmName()), wktNumber(p.from_greenwich/d2r), degree)
+
+	var crs string
+	switch {
+	case p.proj == "geocent":
+		crs = wkt("GEODCRS", wktQuote("unknown"), datum, primem, "CS[Cartesian,3]",
+			wkt("AXIS", wktQuote("(X)"), "geocentricX", "ORDER[1]", length),
+			wkt("AXIS", wktQuote("(Y)"), "geocentricY", "ORDER[2]", length),
+			wkt("AXIS", wktQuote("(Z)"), "geocentricZ", "ORDER[3]", length))
+	case proj.IsLngLat():
+		items := []string{wktQuote("unknown"), datum, primem, "CS[ellipsoidal,2]"}
+		crs = wkt("GEOGCRS", append(items, p.wkt2Axes(true, degree)...)...)
+	default:
+		m, values, err := p.conversionMethod()
+		if err != nil {
+			return "", err
+		}
+		conversion := p.wkt2Conversion(m, values, degree)
+		base := wkt("BASEGEOGCRS", wktQuote("unknown"), datum, primem, degree)
+		items := []string{wktQuote("unknown"), base, conversion, "CS[Cartesian,2]"}
+		crs = wkt("PROJCRS", append(items, p.wkt2Axes(false, length)...)...)
+	}
+
+	shift := p.wkt2Shift()
+	if shift == "" {
+		return crs, nil
+	}
+	wgs84 := wkt("GEOGCRS", wktQuote("WGS 84"),
+		wkt("DATUM", wktQuote("World Geodetic System 1984"),
+			wkt("ELLIPSOID", wktQuote("WGS 84"), "6378137", "298.257223563", wkt("LENGTHUNIT", wktQuote("metre"), "1"))),
+		wkt("PRIMEM", wktQuote("Greenwich"), "0", degree),
+		"CS[ellipsoidal,2]",
+		wkt("AXIS", wktQuote("latitude"), "north", "ORDER[1]", degree),
+		wkt("AXIS", wktQuote("longitude"), "east", "ORDER[2]", degree),
+		wkt("ID", wktQuote("EPSG"), "4326"))
+	return wkt("BOUNDCRS", wkt("SOURCECRS", crs), wkt("TARGETCRS", wgs84), shift), nil
+}
+
+// wkt2DatumNames are the EPSG names of the datums in datums_list.
+var wkt2DatumNames = map[string]string{
+	"WGS84":         "World Geodetic System 1984",
+	"GGRS87":        "Greek Geodetic Reference System 1987",
+	"NAD83":         "North American Datum 1983",
+	"NAD27":         "North American Datum 1927",
+	"potsdam":       "Deutsches Hauptdreiecksnetz",
+	"carthage":      "Carthage",
+	"hermannskogel": "Hermannskogel",
+	"ire65":         "Ireland 1965",
+	"nzgd49":        "New Zealand Geodetic Datum 1949",
+	"OSGB36":        "Ordnance Survey of Great Britain 1936",
+}
+
+// datumNames finds the names of the datum and ellipsoid in defs.go.
+func (p *pj) datumNames() (datum, ellps string) {
+	datum, ellps = "unknown", "unknown"
+	if id, ok := p.params.string("datum"); ok {
+		if n, ok := wkt2DatumNames[id]; ok {
+			datum = n
+		}
+	}
+	if id, ok := p.params.string("ellps"); ok {
+		if e, ok := ellipse_list[id]; ok {
+			ellps = e.name
+		}
+	}
+	return datum, ellps
+}
+
+// pmName finds the name of the prime meridian in defs.go.
+func (p *pj) pmName() string {
+	if name, ok := p.params.string("pm"); ok {
+		if _, ok := pm_list[name]; ok {
+			return strings.ToUpper(name[:1]) + name[1:]
+		}
+		return "unknown"
+	}
+	return "Greenwich"
+}
+
+// rf is the inverse flattening of the original ellipsoid, or 0 for a sphere.
+func (p *pj) rf() float64 {
+	if p.esOrig == 0 {
+		return 0
+	}
+	return 1 / (1 - math.Sqrt(1-p.esOrig))
+}
+
+// wkt2Datum writes the datum and its ellipsoid.
+func (p *pj) wkt2Datum() string {
+	name, ellps := p.datumNames()
+	rf := p.rf()
+	return wkt("DATUM", wktQuote(name),
+		wkt("ELLIPSOID", wktQuote(ellps), wktNumber(p.aOrig), wktNumber(rf), wkt("LENGTHUNIT", wktQuote("metre"), "1")))
+}
+
+// wkt2Axes writes the first two axes from +axis.
+func (p *pj) wkt2Axes(geographic bool, unit string) []string {
+	var axes []string
+	for i, dir := range p.axis[:2] {
+		var name, direction string
+		switch dir {
+		case 'e', 'w':
+			name, direction = "longitude", map[rune]string{'e': "east", 'w': "west"}[dir]
+		case 'n', 's':
+			name, direction = "latitude", map[rune]string{'n': "north", 's': "south"}[dir]
+		}
+		if !geographic {
+			name = "(" + strings.ToUpper(direction[:1]) + ")"
+		}
+		axes = append(axes, wkt("AXIS", wktQuote(name), direction, "ORDER["+strconv.Itoa(i+1)+"]", unit))
+	}
+	return axes
+}
+
+// conversionMethod picks the method that describes the projection, and the
+// values of its parameters by EPSG code, in radians, metres or unity.
+func (p *pj) conversionMethod() (*wkt2Method, map[string]float64, error) {
+	lat1, _ := p.params.degree("lat_1")
+	lat2, hasLat2 := p.params.degree("lat_2")
+	latTs, hasLatTs := p.params.degree("lat_ts")
+	if !hasLat2 {
+		lat2 = lat1
+	}
+
+	var code string
+	switch p.proj {
+	case "tmerc", "utm":
+		code = "9807"
+	case "merc":
+		code = "9804"
+		if hasLatTs {
+			code, lat1 = "9805", latTs
+		}
+	case "lcc":
+		code = "9802"
+		if math.Abs(lat1-lat2) < epsln && math.Abs(lat1-p.phi0) < epsln {
+			code = "9801"
+		}
+	case "stere":
+		if math.Abs(math.Abs(p.phi0)-half_pi) >= epsln {
+			return p.methodValues(wkt2MethodByName("Stereographic"), 0, 0, 0)
+		}
+		code = "9810"
+		if hasLatTs && math.Abs(math.Abs(latTs)-half_pi) >= epsln {
+			code = "9829"
+		}
+	case "eqc":
+		code = "1028"
+		if hasLatTs {
+			lat1 = latTs
+		}
+	case "gnom":
+		return p.methodValues(wkt2MethodByName("Gnomonic"), 0, 0, 0)
+	default:
+		for _, m := range wkt2Methods {
+			if m.proj == p.proj && m.code != "" {
+				code = m.code
+				break
+			}
+		}
+	}
+	for i := range wkt2Methods {
+		if wkt2Methods[i].code == code && code != "" {
+			return p.methodValues(&wkt2Methods[i], lat1, lat2, latTs)
+		}
+	}
+	return nil, nil, ErrUnsupportedProj
+}
+
+func wkt2MethodByName(name string) *wkt2Method {
+	for i := range wkt2Methods {
+		if wkt2Methods[i].name == name {
+			return &wkt2Methods[i]
+		}
+	}
+	return nil
+}
+
+func (p *pj) methodValues(m *wkt2Method, lat1, lat2, latTs float64) (*wkt2Method, map[string]float64, error) {
+	values := make(map[string]float64)
+	for _, code := range m.params {
+		var v float64
+		switch code {
+		case "8801", "8821":
+			v = p.phi0
+		case "8802", "8822", "8833":
+			v = p.lam0
+		case "8805":
+			v = p.k0
+		case "8806", "8826":
+			v = p.x0
+		case "8807", "8827":
+			v = p.y0
+		case "8823":
+			v = lat1
+		case "8824":
+			v = lat2
+		case "8832":
+			v = latTs
+		}
+		values[code] = v
+	}
+	return m, values, nil
+}
+
+// conversionName names the conversion, which we only know for UTM.
+func (p *pj) conversionName() string {
+	if p.proj != "utm" {
+		return "unknown"
+	}
+	name := "UTM zone " + p.params["zone"] + "N"
+	if south, _ := p.params.bool("south"); south {
+		name = name[:len(name)-1] + "S"
+	}
+	return name
+}
+
+// wkt2Conversion writes the projection method and its parameters.
+func (p *pj) wkt2Conversion(m *wkt2Method, values map[string]float64, degree string) string {
+	method := []string{wktQuote(m.name)}
+	if m.code != "" {
+		method = append(method, wkt("ID", wktQuote("EPSG"), m.code))
+	}
+	items := []string{wktQuote(p.conversionName()), wkt("METHOD", method...)}
+	scale := wkt("SCALEUNIT", wktQuote("unity"), "1")
+	metre := wkt("LENGTHUNIT", wktQuote("metre"), "1")
+	for _, code := range m.params {
+		v := values[code]
+		param := wkt2ParamByCode(code)
+		var value, unit string
+		switch param.kind {
+		case wkt2Angle:
+			value, unit = wktNumber(v/d2r), degree
+		case wkt2Length:
+			value, unit = wktNumber(v), metre
+		case wkt2Scale:
+			value, unit = wktNumber(v), scale
+		}
+		items = append(items, wkt("PARAMETER", wktQuote(param.name), value, unit, wkt("ID", wktQuote("EPSG"), code)))
+	}
+	return wkt("CONVERSION", items...)
+}
+
+// towgs84 returns the Helmert shift to WGS84 as it was given, rather than
+// converted, or nil if there isn't one worth describing.
+func (p *pj) towgs84() []float64 {
+	if p.datumParams == nil || p.params["datum"] == "WGS84" {
+		return nil
+	}
+	var values []float64
+	for _, v := range strings.Split(p.params["towgs84"], ",") {
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		values = append(values, f)
+	}
+	return values
+}
+
+// helmertMethod names the EPSG method for a 3 or 7 parameter shift.
+func helmertMethod(values []float64) (name, code string) {
+	if len(values) == 7 {
+		return "Position Vector transformation (geog2D domain)", "9606"
+	}
+	return "Geocentric translations (geog2D domain)", "9603"
+}
+
+// wkt2Shift writes the transformation to WGS84, if there is one.
+func (p *pj) wkt2Shift() string {
+	name := wktQuote("Transformation to WGS84")
+	if p.datumType == PJD_GRIDSHIFT && p.nadgrids != "" {
+		return wkt("ABRIDGEDTRANSFORMATION", name,
+			wkt("METHOD", wktQuote("NTv2"), wkt("ID", wktQuote("EPSG"), "9615")),
+			wkt("PARAMETERFILE", wktQuote("Latitude and longitude difference file"), wktQuote(p.nadgrids)))
+	}
+	values := p.towgs84()
+	if values == nil {
+		return ""
+	}
+
+	methodName, code := helmertMethod(values)
+	method := wkt("METHOD", wktQuote(methodName), wkt("ID", wktQuote("EPSG"), code))
+	units := map[wkt2Kind]string{
+		wkt2Length: wkt("LENGTHUNIT", wktQuote("metre"), "1"),
+		wkt2Angle:  wkt("ANGLEUNIT", wktQuote("arc-second"), "4.84813681109536E-06"),
+		wkt2Scale:  wkt("SCALEUNIT", wktQuote("parts per million"), "1E-06"),
+	}
+	items := []string{name, method}
+	for i, v := range values {
+		param := wkt2Shifts[i]
+		items = append(items, wkt("PARAMETER", wktQuote(param.name), wktNumber(v), units[param.kind], wkt("ID", wktQuote("EPSG"), param.code)))
+	}
+	return wkt("ABRIDGEDTRANSFORMATION", items...)
+}
+
+// unitName finds the name of a length unit from units_list.
+func unitName(toMeter float64) string {
+	if toMeter == 1 {
+		return "metre"
+	}
+	for _, u := range units_list {
+		if math.Abs(u.to_meter/toMeter-1) < 1e-12 {
+			return u.name
+		}
+	}
+	return "unknown"
+}
+
+func wkt(keyword string, items ...string) string {
+	return keyword + "[" + strings.Join(items, ",") + "]"
+}
+
+func wktQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// wktNumber rounds away the noise from converting radians to degrees.
+func wktNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', 15, 64)
+}
@@ -0,0 +1,291 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"strings"
+	"testing"
+)
+
+const wgs84GEOGCRS = `GEOGCRS["WGS 84",
+    ENSEMBLE["World Geodetic System 1984 ensemble",
+        MEMBER["World Geodetic System 1984 (Transit)"],
+        MEMBER["World Geodetic System 1984 (G730)"],
+        ELLIPSOID["WGS 84",6378137,298.257223563,
+            LENGTHUNIT["metre",1]],
+        ENSEMBLEACCURACY[2.0]],
+    PRIMEM["Greenwich",0,
+        ANGLEUNIT["degree",0.0174532925199433]],
+    CS[ellipsoidal,2],
+        AXIS["geodetic latitude (Lat)",north,
+            ORDER[1],
+            ANGLEUNIT["degree",0.0174532925199433]],
+        AXIS["geodetic longitude (Lon)",east,
+            ORDER[2],
+            ANGLEUNIT["degree",0.0174532925199433]],
+    USAGE[
+        SCOPE["Horizontal component of 3D system."],
+        AREA["World."],
+        BBOX[-90,-180,90,180]],
+    ID["EPSG",4326]]`
+
+var wkt2Tests = []struct {
+	name, wkt, proj4 string
+}{
+	{"UTM", `PROJCRS["WGS 84 / UTM zone 33N",
+    BASEGEOGCRS["WGS 84",
+        ENSEMBLE["World Geodetic System 1984 ensemble",
+            MEMBER["World Geodetic System 1984 (Transit)"],
+            ELLIPSOID["WGS 84",6378137,298.257223563,
+                LENGTHUNIT["metre",1]],
+            ENSEMBLEACCURACY[2.0]],
+        PRIMEM["Greenwich",0,
+            ANGLEUNIT["degree",0.0174532925199433]],
+        ID["EPSG",4326]],
+    CONVERSION["UTM zone 33N",
+        METHOD["Transverse Mercator",
+            ID["EPSG",9807]],
+        PARAMETER["Latitude of natural origin",0,
+            ANGLEUNIT["degree",0.0174532925199433],
+            ID["EPSG",8801]],
+        PARAMETER["Longitude of natural origin",15,
+            ANGLEUNIT["degree",0.0174532925199433],
+            ID["EPSG",8802]],
+        PARAMETER["Scale factor at natural origin",0.9996,
+            SCALEUNIT["unity",1],
+            ID["EPSG",8805]],
+        PARAMETER["False easting",500000,
+            LENGTHUNIT["metre",1],
+            ID["EPSG",8806]],
+        PARAMETER["False northing",0,
+            LENGTHUNIT["metre",1],
+            ID["EPSG",8807]]],
+    CS[Cartesian,2],
+        AXIS["(E)",east,
+            ORDER[1],
+            LENGTHUNIT["metre",1]],
+        AXIS["(N)",north,
+            ORDER[2],
+            LENGTHUNIT["metre",1]],
+    USAGE[
+        SCOPE["Engineering survey, topographic mapping."],
+        AREA["Between 12°E and 18°E, northern hemisphere between equator and 84°N, onshore and offshore."],
+        BBOX[0,12,84,18]],
+    ID["EPSG",32633]]`, "+proj=utm +zone=33 +datum=WGS84"},
+	{"bound British National Grid", `BOUNDCRS[
+    SOURCECRS[
+        PROJCRS["OSGB36 / British National Grid",
+            BASEGEOGCRS["OSGB36",
+                DATUM["Ordnance Survey of Great Britain 1936",
+                    ELLIPSOID["Airy 1830",6377563.396,299.3249646,
+                        LENGTHUNIT["metre",1]]],
+                PRIMEM["Greenwich",0,
+                    ANGLEUNIT["degree",0.0174532925199433]]],
+            CONVERSION["British National Grid",
+                METHOD["Transverse Mercator",
+                    ID["EPSG",9807]],
+                PARAMETER["Latitude of natural origin",49,
+                    ANGLEUNIT["degree",0.0174532925199433]],
+                PARAMETER["Longitude of natural origin",-2,
+                    ANGLEUNIT["degree",0.0174532925199433]],
+                PARAMETER["Scale factor at natural origin",0.9996012717,
+                    SCALEUNIT["unity",1]],
+                PARAMETER["False easting",400,
+                    LENGTHUNIT["kilometre",1000]],
+                PARAMETER["False northing",-100000,
+                    LENGTHUNIT["metre",1]]],
+            CS[Cartesian,2],
+                AXIS["(E)",east,
+                    ORDER[1],
+                    LENGTHUNIT["metre",1]],
+                AXIS["(N)",north,
+                    ORDER[2],
+                    LENGTHUNIT["metre",1]]]],
+    TARGETCRS[` + wgs84GEOGCRS + `],
+    ABRIDGEDTRANSFORMATION["OSGB36 to WGS 84 (6)",
+        METHOD["Coordinate Frame rotation (geog2D domain)",
+            ID["EPSG",9607]],
+        PARAMETER["X-axis translation",446.448,
+            ID["EPSG",8605]],
+        PARAMETER["Y-axis translation",-125.157,
+            ID["EPSG",8606]],
+        PARAMETER["Z-axis translation",542.06,
+            ID["EPSG",8607]],
+        PARAMETER["X-axis rotation",-0.15,
+            ID["EPSG",8608]],
+        PARAMETER["Y-axis rotation",-0.247,
+            ID["EPSG",8609]],
+        PARAMETER["Z-axis rotation",-0.842,
+            ID["EPSG",8610]],
+        PARAMETER["Scale difference",-20.489,
+            ID["EPSG",8611]]]]`,
+		"+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +a=6377563.396 +rf=299.3249646 +towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489"},
+	{"polar stereographic", `PROJCRS["WGS 84 / Antarctic Polar Stereographic",
+    BASEGEODCRS["WGS 84",
+        DATUM["World Geodetic System 1984",
+            ELLIPSOID["WGS 84",6378137,298.257223563]],
+        UNIT["degree",0.0174532925199433]],
+    CONVERSION["Antarctic Polar Stereographic",
+        METHOD["Polar Stereographic (variant B)"],
+        PARAMETER["Latitude of standard parallel",-71],
+        PARAMETER["Longitude of origin",0],
+        PARAMETER["False easting",0],
+        PARAMETER["False northing",0]],
+    CS[Cartesian,2],
+        AXIS["easting (E)",north,ORDER[1]],
+        AXIS["northing (N)",north,ORDER[2]],
+    LENGTHUNIT["metre",1]]`,
+		"+proj=stere +lat_0=-90 +lat_ts=-71 +lon_0=0 +datum=WGS84"},
+}
+
+func TestNewProjectionFromWKT2(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	for _, test := range wkt2Tests {
+		fromWKT, err := NewProjectionFromWKT(test.wkt)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		expected, err := NewProjection(test.proj4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ll := range [][2]float64{{-1.5, 52.25}, {16.75, 70.5}, {160, -78.5}} {
+			x1, y1, z1 := []float64{ll[0] * d2r}, []float64{ll[1] * d2r}, []float64{0}
+			x2, y2, z2 := []float64{ll[0] * d2r}, []float64{ll[1] * d2r}, []float64{0}
+			if err := Transform(wgs84, fromWKT, x1, y1, z1); err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				continue
+			}
+			Transform(wgs84, expected, x2, y2, z2)
+			if !within(x1[0], x2[0], 1.0e-6) || !within(y1[0], y2[0], 1.0e-6) || !within(z1[0], z2[0], 1.0e-6) {
+				t.Errorf("%s at %v: (%f, %f, %f) - (%f, %f, %f)", test.name, ll, x1[0], y1[0], z1[0], x2[0], y2[0], z2[0])
+			}
+		}
+	}
+
+	// the axes of EPSG:4326 are latitude first
+	root, _ := parseWKT(wgs84GEOGCRS)
+	parms, err := wkt2Params(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parms["proj"] != "longlat" || parms["datum"] != "WGS84" || parms["axis"] != "neu" {
+		t.Errorf("wrong params for EPSG:4326: %v", parms)
+	}
+
+	// the polar stereographic test has two northings, which isn't an +axis
+	root, _ = parseWKT(wkt2Tests[2].wkt)
+	if parms, _ = wkt2Params(root); parms["axis"] != "" {
+		t.Errorf("expected no axis, got %q", parms["axis"])
+	}
+
+	for _, wkt := range []string{
+		`PROJCRS["no base",CONVERSION["UTM zone 33N",METHOD["Transverse Mercator"]]]`,
+		`BOUNDCRS[TARGETCRS[` + wgs84GEOGCRS + `]]`,
+		`GEOGCRS["no ellipsoid",DATUM["World Geodetic System 1984"]]`,
+	} {
+		if _, err := NewProjectionFromWKT(wkt); err != ErrInvalidWKT {
+			t.Errorf("%q: expected ErrInvalidWKT, got %v", wkt, err)
+		}
+	}
+	hom := `PROJCRS["Hotine",BASEGEOGCRS["WGS 84",DATUM["WGS 84",ELLIPSOID["WGS 84",6378137,298.257223563]]],CONVERSION["Hotine",METHOD["Hotine Oblique Mercator (variant A)",ID["EPSG",9812]]]]`
+	if _, err := NewProjectionFromWKT(hom); err != ErrUnsupportedProj {
+		t.Errorf("expected ErrUnsupportedProj, got %v", err)
+	}
+}
+
+func TestFormatWKT2(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	for _, test := range []struct {
+		proj4    string
+		lng, lat float64
+		contains []string
+	}{
+		{"+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +datum=OSGB36", -1.5, 52.25,
+			[]string{`BOUNDCRS[SOURCECRS[PROJCRS[`, `DATUM["Ordnance Survey of Great Britain 1936",ELLIPSOID["Airy 1830",6377563.396,299.324975315032,`,
+				`METHOD["Transverse Mercator",ID["EPSG",9807]]`, `PARAMETER["Scale factor at natural origin",0.9996012717,`,
+				`METHOD["Position Vector transformation (geog2D domain)",ID["EPSG",9606]]`,
+				`PARAMETER["Scale difference",-20.4894,SCALEUNIT["parts per million",1E-06],ID["EPSG",8611]]`}},
+		{"+proj=utm +zone=33 +south +datum=WGS84", 16.75, -30.5,
+			[]string{`CONVERSION["UTM zone 33S",`, `DATUM["World Geodetic System 1984"`, `AXIS["(N)",north,ORDER[2],LENGTHUNIT["metre",1]]`}},
+		{"+proj=merc +lon_0=10 +k=0.98 +ellps=GRS80 +towgs84=0,0,0", 12, 40,
+			[]string{`METHOD["Mercator (variant A)",ID["EPSG",9804]]`, `ELLIPSOID["GRS 1980(IUGG, 1980)"`,
+				`METHOD["Geocentric translations (geog2D domain)",ID["EPSG",9603]]`}},
+		{"+proj=merc +lat_ts=30 +lon_0=10 +ellps=GRS80", 12, 40,
+			[]string{`METHOD["Mercator (variant B)",ID["EPSG",9805]]`, `PARAMETER["Latitude of 1st standard parallel",30,`}},
+		{"+proj=merc +R=6378137 +nadgrids=@null", 12, 40,
+			[]string{`ELLIPSOID["unknown",6378137,0,`, `PARAMETERFILE["Latitude and longitude difference file","@null"]`}},
+		{"+proj=lcc +lat_1=33 +lat_2=45 +lat_0=23 +lon_0=-96 +x_0=0 +y_0=0 +datum=NAD83", -100, 40,
+			[]string{`METHOD["Lambert Conic Conformal (2SP)",ID["EPSG",9802]]`, `PARAMETER["Latitude of false origin",23,`}},
+		{"+proj=lcc +lat_1=18 +lat_0=18 +lon_0=-77 +k_0=0.9998 +x_0=250000 +y_0=150000 +ellps=clrk66", -76.5, 18.25,
+			[]string{`METHOD["Lambert Conic Conformal (1SP)",ID["EPSG",9801]]`}},
+		{"+proj=stere +lat_0=90 +lat_ts=70 +lon_0=-45 +datum=WGS84", -40, 75,
+			[]string{`METHOD["Polar Stereographic (variant B)",ID["EPSG",9829]]`, `PARAMETER["Latitude of standard parallel",70,`}},
+		{"+proj=stere +lat_0=-90 +lon_0=0 +k=0.994 +x_0=2000000 +y_0=2000000 +datum=WGS84", 30, -80,
+			[]string{`METHOD["Polar Stereographic (variant A)",ID["EPSG",9810]]`}},
+		{"+proj=stere +lat_0=40 +lon_0=-3 +ellps=intl", -2, 41,
+			[]string{`METHOD["Stereographic"]`}},
+		{"+proj=sterea +lat_0=52.15616055555555 +lon_0=5.38763888888889 +k=0.9999079 +x_0=155000 +y_0=463000 +ellps=bessel", 6, 52,
+			[]string{`METHOD["Oblique Stereographic",ID["EPSG",9809]]`}},
+		{"+proj=aea +lat_1=29.5 +lat_2=45.5 +lat_0=37.5 +lon_0=-96 +datum=NAD83", -100, 40,
+			[]string{`METHOD["Albers Equal Area",ID["EPSG",9822]]`, `PARAMETER["Latitude of 2nd standard parallel",45.5,`}},
+		{"+proj=laea +lat_0=52 +lon_0=10 +x_0=4321000 +y_0=3210000 +ellps=GRS80", 5, 50,
+			[]string{`METHOD["Lambert Azimuthal Equal Area",ID["EPSG",9820]]`}},
+		{"+proj=aeqd +lat_0=40 +lon_0=-100 +ellps=WGS84", -90, 45,
+			[]string{`METHOD["Azimuthal Equidistant",ID["EPSG",1125]]`}},
+		{"+proj=ortho +lat_0=40 +lon_0=-100 +R=6371000", -90, 45,
+			[]string{`METHOD["Orthographic",ID["EPSG",9840]]`}},
+		{"+proj=gnom +lat_0=40 +lon_0=-100 +R=6371000", -90, 45,
+			[]string{`METHOD["Gnomonic"]`}},
+		{"+proj=eqc +lat_ts=30 +lon_0=10 +ellps=WGS84", 12, 40,
+			[]string{`METHOD["Equidistant Cylindrical",ID["EPSG",1028]]`}},
+		{"+proj=longlat +datum=NAD27", -90, 45,
+			[]string{`GEOGCRS["unknown",DATUM["North American Datum 1927",ELLIPSOID["Clarke 1866"`, `CS[ellipsoidal,2]`,
+				`AXIS["longitude",east,ORDER[1]`, `PARAMETERFILE["Latitude and longitude difference file","@conus,@alaska,@ntv2_0.gsb,@ntv1_can.dat"]`}},
+		{"+proj=geocent +datum=WGS84", -90, 45,
+			[]string{`GEODCRS["unknown"`, `CS[Cartesian,3]`, `AXIS["(Z)",geocentricZ,ORDER[3],LENGTHUNIT["metre",1]]`}},
+	} {
+		proj, err := NewProjection(test.proj4)
+		if err != nil {
+			t.Fatalf("%s: %v", test.proj4, err)
+		}
+		wkt, err := FormatWKT2(proj)
+		if err != nil {
+			t.Errorf("%s: %v", test.proj4, err)
+			continue
+		}
+		for _, s := range test.contains {
+			if !strings.Contains(wkt, s) {
+				t.Errorf("%s: expected %s in %s", test.proj4, s, wkt)
+			}
+		}
+
+		// and it reads back as the same projection
+		back, err := NewProjectionFromWKT(wkt)
+		if err != nil {
+			t.Errorf("%s: %v in %s", test.proj4, err, wkt)
+			continue
+		}
+		x1, y1, z1 := []float64{test.lng * d2r}, []float64{test.lat * d2r}, []float64{0}
+		x2, y2, z2 := []float64{test.lng * d2r}, []float64{test.lat * d2r}, []float64{0}
+		if strings.Contains(test.proj4, "nadgrids") || strings.Contains(test.proj4, "NAD27") {
+			// there's no need for grids to compare the projections
+			x1[0], y1[0], _ = proj.Forward(x1[0], y1[0])
+			x2[0], y2[0], _ = back.Forward(x2[0], y2[0])
+		} else {
+			if err := Transform(wgs84, proj, x1, y1, z1); err != nil {
+				t.Fatalf("%s: %v", test.proj4, err)
+			}
+			if err := Transform(wgs84, back, x2, y2, z2); err != nil {
+				t.Errorf("%s: %v in %s", test.proj4, err, wkt)
+				continue
+			}
+		}
+		if !within(x1[0], x2[0], 1.0e-6) || !within(y1[0], y2[0], 1.0e-6) || !within(z1[0], z2[0], 1.0e-6) {
+			t.Errorf("%s: (%f, %f, %f) - (%f, %f, %f) from %s", test.proj4, x1[0], y1[0], z1[0], x2[0], y2[0], z2[0], wkt)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2015 Sam L'ecuyer. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package projectron
+
+import (
+	"testing"
+)
+
+const wgs84GEOGCS = `GEOGCS["WGS 84",
+	DATUM["WGS_1984",
+		SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],
+		AUTHORITY["EPSG","6326"]],
+	PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],
+	UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],
+	AXIS["Latitude",NORTH],
+	AXIS["Longitude",EAST],
+	AUTHORITY["EPSG","4326"]]`
+
+var wktTests = []struct {
+	name, wkt, proj4 string
+}{
+	{"OGC British National Grid",
+		`PROJCS["OSGB 1936 / British National Grid",
+			GEOGCS["OSGB 1936",
+				DATUM["OSGB_1936",
+					SPHEROID["Airy 1830",6377563.396,299.3249646,AUTHORITY["EPSG","7001"]],
+					TOWGS84[446.448,-125.157,542.06,0.15,0.247,0.842,-20.489],
+					AUTHORITY["EPSG","6277"]],
+				PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],
+				UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],
+				AUTHORITY["EPSG","4277"]],
+			PROJECTION["Transverse_Mercator"],
+			PARAMETER["latitude_of_origin",49],
+			PARAMETER["central_meridian",-2],
+			PARAMETER["scale_factor",0.9996012717],
+			PARAMETER["false_easting",400000],
+			PARAMETER["false_northing",-100000],
+			UNIT["metre",1,AUTHORITY["EPSG","9001"]],
+			AXIS["Easting",EAST],
+			AXIS["Northing",NORTH],
+			AUTHORITY["EPSG","27700"]]`,
+		"+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +a=6377563.396 +rf=299.3249646 +towgs84=446.448,-125.157,542.06,0.15,0.247,0.842,-20.489"},
+	{"ESRI UTM",
+		`PROJCS["WGS_1984_UTM_Zone_33N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",15.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`,
+		"+proj=utm +zone=33 +datum=WGS84"},
+	{"OGC geographic", wgs84GEOGCS, "+proj=longlat +datum=WGS84"},
+	{"OGC polar stereographic",
+		`PROJCS["WGS 84 / NSIDC Sea Ice Polar Stereographic North",` + wgs84GEOGCS + `,
+			PROJECTION["Polar_Stereographic"],
+			PARAMETER["latitude_of_origin",70],
+			PARAMETER["central_meridian",-45],
+			PARAMETER["scale_factor",1],
+			PARAMETER["false_easting",0],
+			PARAMETER["false_northing",0],
+			UNIT["metre",1,AUTHORITY["EPSG","9001"]],
+			AUTHORITY["EPSG","3413"]]`,
+		"+proj=stere +lat_0=90 +lat_ts=70 +lon_0=-45 +k=1 +x_0=0 +y_0=0 +datum=WGS84"},
+	{"OGC LCC 1SP",
+		`PROJCS["JAD69 / Jamaica National Grid",
+			GEOGCS["JAD69",
+				DATUM["Jamaica_1969",
+					SPHEROID["Clarke 1866",6378206.4,294.9786982139006,AUTHORITY["EPSG","7008"]],
+					TOWGS84[70,207,389.5,0,0,0,0]],
+				PRIMEM["Greenwich",0],
+				UNIT["degree",0.0174532925199433]],
+			PROJECTION["Lambert_Conformal_Conic_1SP"],
+			PARAMETER["latitude_of_origin",18],
+			PARAMETER["central_meridian",-77],
+			PARAMETER["scale_factor",1],
+			PARAMETER["false_easting",250000],
+			PARAMETER["false_northing",150000],
+			UNIT["metre",1]]`,
+		"+proj=lcc +lat_1=18 +lat_0=18 +lon_0=-77 +k_0=1 +x_0=250000 +y_0=150000 +ellps=clrk66 +towgs84=70,207,389.5"},
+	{"ESRI web mercator",
+		`PROJCS["WGS_1984_Web_Mercator_Auxiliary_Sphere",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Mercator_Auxiliary_Sphere"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",0.0],PARAMETER["Standard_Parallel_1",0.0],PARAMETER["Auxiliary_Sphere_Type",0.0],UNIT["Meter",1.0]]`,
+		"+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +nadgrids=@null"},
+	{"GDAL proj4 extension",
+		`PROJCS["WGS 84 / Pseudo-Mercator",` + wgs84GEOGCS + `,
+			PROJECTION["Mercator_1SP"],
+			PARAMETER["central_meridian",0],
+			PARAMETER["scale_factor",1],
+			PARAMETER["false_easting",0],
+			PARAMETER["false_northing",0],
+			UNIT["metre",1],
+			EXTENSION["PROJ4","+proj=merc +a=6378137 +b=6378137 +lat_ts=0.0 +lon_0=0.0 +x_0=0.0 +y_0=0 +k=1.0 +units=m +nadgrids=@null +wktext +no_defs"],
+			AUTHORITY["EPSG","3857"]]`,
+		"+proj=merc +R=6378137 +nadgrids=@null"},
+}
+
+func TestNewProjectionFromWKT(t *testing.T) {
+	wgs84, _ := NewProjection("+proj=longlat +datum=WGS84")
+	for _, test := range wktTests {
+		fromWKT, err := NewProjectionFromWKT(test.wkt)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		expected, err := NewProjection(test.proj4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fromWKT.IsLngLat() != expected.IsLngLat() {
+			t.Errorf("%s: IsLngLat is %v", test.name, fromWKT.IsLngLat())
+		}
+		for _, ll := range [][2]float64{{-1.5, 52.25}, {16.75, 70.5}, {-76.5, 18.25}} {
+			x1, y1, z1 := []float64{ll[0] * d2r}, []float64{ll[1] * d2r}, []float64{0}
+			x2, y2, z2 := []float64{ll[0] * d2r}, []float64{ll[1] * d2r}, []float64{0}
+			if err := Transform(wgs84, fromWKT, x1, y1, z1); err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				continue
+			}
+			Transform(wgs84, expected, x2, y2, z2)
+			if !within(x1[0], x2[0], 1.0e-6) || !within(y1[0], y2[0], 1.0e-6) || !within(z1[0], z2[0], 1.0e-6) {
+				t.Errorf("%s at %v: (%f, %f, %f) - (%f, %f, %f)", test.name, ll, x1[0], y1[0], z1[0], x2[0], y2[0], z2[0])
+			}
+		}
+	}
+}
+
+func TestWKTParams(t *testing.T) {
+	for _, test := range []struct {
+		wkt      string
+		expected map[string]string
+	}{
+		// lengths are in the PROJCS's unit
+		{`PROJCS["NAD83 / New York Long Island (ftUS)",
+			GEOGCS["NAD83",
+				DATUM["North_American_Datum_1983",
+					SPHEROID["GRS 1980",6378137,298.257222101]],
+				PRIMEM["Greenwich",0],
+				UNIT["degree",0.0174532925199433]],
+			PROJECTION["Lambert_Conformal_Conic_2SP"],
+			PARAMETER["standard_parallel_1",41.03333333333333],
+			PARAMETER["standard_parallel_2",40.66666666666666],
+			PARAMETER["latitude_of_origin",40.16666666666666],
+			PARAMETER["central_meridian",-74],
+			PARAMETER["false_easting",984250.0000000002],
+			PARAMETER["false_northing",0],
+			UNIT["US survey foot",0.3048006096012192]]`,
+			map[string]string{"proj": "lcc", "datum": "NAD83", "lat_0": "40.16666666666666",
+				"lon_0": "-74", "x_0": "300000", "y_0": "0", "to_meter": "0.3048006096012192"}},
+		// angles, including the prime meridian, are in the GEOGCS's
+		{`GEOGCS["NTF (Paris)",
+			DATUM["Nouvelle_Triangulation_Francaise_Paris",
+				SPHEROID["Clarke 1880 (IGN)",6378249.2,293.4660212936269],
+				TOWGS84[-168,-60,320,0,0,0,0]],
+			PRIMEM["Paris",2.5969213],
+			UNIT["grad",0.01570796326794897]]`,
+			map[string]string{"proj": "longlat", "pm": "2.33722917", "towgs84": "-168,-60,320",
+				"a": "6378249.2", "rf": "293.4660212936269"}},
+		// ESRI datum names and spheres
+		{`GEOGCS["GCS_Sphere",DATUM["D_North_American_1927",SPHEROID["Sphere",6371000.0,0.0]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+			map[string]string{"proj": "longlat", "R": "6371000", "datum": "NAD27"}},
+		{`GEOCCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],PRIMEM["Greenwich",0],UNIT["kilometre",1000]]`,
+			map[string]string{"proj": "geocent", "to_meter": "1000", "datum": "WGS84"}},
+	} {
+		root, err := parseWKT(test.wkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parms, err := wkt1Params(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, val := range test.expected {
+			if v, ok := parms[key]; !ok {
+				t.Errorf("%s: missing %s", root.values[0], key)
+			} else if val != v {
+				f1, ok1 := parms.float(key)
+				f2, ok2 := paramset{key: val}.float(key)
+				if !ok1 || !ok2 || !within(f1, f2, 1.0e-9) {
+					t.Errorf("%s: %s is %s, not %s", root.values[0], key, v, val)
+				}
+			}
+		}
+	}
+}
+
+func TestParseWKT(t *testing.T) {
+	root, err := parseWKT(` VERT_CS("height ""above"" sea",VERT_DATUM["Ordnance Datum Newlyn",2005],UNIT["metre",1], AXIS["Up",UP] ) `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.keyword != "VERT_CS" || root.value(0) != `height "above" sea` || len(root.children) != 3 {
+		t.Errorf("parsed wrong: %+v", root)
+	}
+	if axis := root.child("axis"); axis == nil || axis.value(1) != "UP" {
+		t.Errorf("parsed axis wrong: %+v", axis)
+	}
+
+	for _, wkt := range []string{
+		``,
+		`GEOGCS`,
+		`GEOGCS["WGS 84"`,
+		`GEOGCS["WGS 84]`,
+		`GEOGCS["WGS 84",DATUM["WGS_1984")]`,
+		`GEOGCS["WGS 84"] trailing`,
+		`GEOGCS["WGS 84",PRIMEM["Greenwich",0]]`,
+	} {
+		if _, err := NewProjectionFromWKT(wkt); err != ErrInvalidWKT {
+			t.Errorf("%q: expected ErrInvalidWKT, got %v", wkt, err)
+		}
+	}
+
+	hom := `PROJCS["Hotine",` + wgs84GEOGCS + `,PROJECTION["Hotine_Oblique_Mercator"],UNIT["metre",1]]`
+	for _, wkt := range []string{hom, `VERT_CS["Newlyn",VERT_DATUM["Ordnance Datum Newlyn",2005],UNIT["metre",1]]`} {
+		if _, err := NewProjectionFromWKT(wkt); err != ErrUnsupportedProj {
+			t.Errorf("expected ErrUnsupportedProj, got %v", err)
+		}
+	}
+}